@@ -0,0 +1,51 @@
+package amalgam
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"reflect"
+)
+
+var addrType = reflect.TypeOf((*net.Addr)(nil)).Elem()
+
+// addrNetwork returns the network used to resolve the addresses for a
+// net.Addr field, from the `addrtype` tag option (eg. `addrtype=tcp`).
+func addrNetwork(info fieldInfo) (string, error) {
+	switch network := info.options["addrtype"]; network {
+	case "tcp", "tcp4", "tcp6", "udp", "udp4", "udp6":
+		return network, nil
+	case "":
+		return "", errors.New("net.Addr fields need the addrtype tag option (eg. addrtype=tcp)")
+	default:
+		return "", fmt.Errorf("unsupported addrtype %q", network)
+	}
+}
+
+// parseAddr resolves an address on the network.
+func parseAddr(network, s string) (net.Addr, error) {
+	switch network {
+	case "udp", "udp4", "udp6":
+		return net.ResolveUDPAddr(network, s)
+	default:
+		return net.ResolveTCPAddr(network, s)
+	}
+}
+
+// convertAddr resolves a raw string setting for a net.Addr field.  Other
+// settings (eg. the default address) are returned unchanged.
+func convertAddr(info fieldInfo, raw interface{}) (interface{}, error) {
+	s, ok := raw.(string)
+	if !ok {
+		return raw, nil
+	}
+
+	network, err := addrNetwork(info)
+	if err != nil {
+		return nil, err
+	}
+	if s == "" {
+		return nil, nil
+	}
+	return parseAddr(network, s)
+}
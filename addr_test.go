@@ -0,0 +1,50 @@
+package amalgam
+
+import (
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/spf13/pflag"
+)
+
+func TestAddrFields(t *testing.T) {
+	var config struct {
+		Listen  net.Addr `amalgam:",addrtype=tcp"`
+		Metrics net.Addr `amalgam:",addrtype=udp"`
+	}
+	a := newTestAmalgam(t, &config, []string{"--listen", "127.0.0.1:8080"})
+	loadYAML(t, a, "metrics: 127.0.0.1:8125\n")
+
+	tcp, ok := config.Listen.(*net.TCPAddr)
+	if !ok || tcp.Port != 8080 || !tcp.IP.Equal(net.IPv4(127, 0, 0, 1)) {
+		t.Errorf("Listen = %#v, want a TCP address for 127.0.0.1:8080", config.Listen)
+	}
+	udp, ok := config.Metrics.(*net.UDPAddr)
+	if !ok || udp.Port != 8125 {
+		t.Errorf("Metrics = %#v, want a UDP address for 127.0.0.1:8125", config.Metrics)
+	}
+}
+
+func TestAddrFieldInvalid(t *testing.T) {
+	var config struct {
+		Listen net.Addr `amalgam:",addrtype=tcp"`
+	}
+	a := newTestAmalgam(t, &config, []string{"--listen", "127.0.0.1:notaport"})
+	if err := a.Load(strings.NewReader("")); err == nil {
+		t.Error("got no error for an invalid address")
+	}
+}
+
+func TestAddrFieldWithoutType(t *testing.T) {
+	var config struct {
+		Listen net.Addr
+	}
+	a := newTestAmalgam(t, &config, nil)
+	if a.flagSet.Lookup("listen") != nil {
+		t.Error("got a --listen flag without an addrtype")
+	}
+
+	_, err := New(&config, WithFlagSet(pflag.NewFlagSet("test", pflag.ContinueOnError)), WithArgs([]string{}), WithStrictDefinition())
+	assertError(t, err, "net.Addr fields need the addrtype tag option")
+}
@@ -2,30 +2,109 @@ package amalgam
 
 import (
 	"bytes"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"net"
+	"net/http"
 	"os"
+	"path/filepath"
 	"reflect"
 	"regexp"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/mitchellh/mapstructure"
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 )
 
 const tagName = "amalgam"
 
+var (
+	ipType       = reflect.TypeOf(net.IP(nil))
+	ipMaskType   = reflect.TypeOf(net.IPMask(nil))
+	durationType = reflect.TypeOf(time.Duration(0))
+	timeType     = reflect.TypeOf(time.Time{})
+)
+
 // Amalgam is the configuration loader object.
 type Amalgam struct {
-	configFile        string
-	configObj         interface{}
-	preventConfigFlag bool
-	envPrefix         string
-	flagNameFunc      func(string) string
-	flagSet           *pflag.FlagSet
-	viper             *viper.Viper
+	configFile           string
+	configDir            string
+	configType           string
+	loadedFile           string
+	loadedLayered        bool
+	baseFile             string
+	overrideFile         string
+	envConfigVar         string
+	configObj            interface{}
+	preventConfigFlag    bool
+	setFlag              bool
+	setValues            []string
+	envPrefix            string
+	envKeyReplacer       *strings.Replacer
+	envSliceSeparator    string
+	envNoSeparator       bool
+	mapEntrySeparator    string
+	mapPairSeparator     string
+	strictEnvPrefix      bool
+	explicitEnv          bool
+	strictDefinition     bool
+	secretPermCheck      bool
+	secretSources        map[string]string
+	secretsDir           string
+	fileValues           map[string]string
+	transforms           []fieldTransform
+	expandEnv            bool
+	strictExpandEnv      bool
+	configVersion        int
+	migrations           map[int]func(map[string]interface{}) map[string]interface{}
+	flagNameFunc         func(string) string
+	descriptionFunc      func(field, desc string, def interface{}, env string) string
+	flagSet              *pflag.FlagSet
+	flagSetName          string
+	ownFlags             []string
+	name                 string
+	args                 []string
+	unknownFlags         bool
+	viper                *viper.Viper
+	fields               fieldMap
+	fileSettings         map[string]interface{}
+	rawFileSettings      map[string]interface{}
+	caseSensitiveKeys    bool
+	autoPersist          bool
+	setFields            map[string]bool
+	typeChecks           bool
+	frozen               bool
+	bareDurationUnit     time.Duration
+	httpClient           *http.Client
+	mu                   sync.RWMutex
+	reloading            bool
+	changedOnly          bool
+	flagsOnly            bool
+	defaults             map[string]interface{}
+	defaultFuncs         []defaultFunc
+	fieldDefaults        map[string]reflect.Value
+	includes             bool
+	profile              string
+	sliceMerge           SliceMergeStrategy
+	keyRewriter          func(string) string
+	decoderTagName       string
+	strictBools          bool
+	interpolateKeys      bool
+	lenientInterpolation bool
+	consumedEnv          []string
+	deferValidation      bool
+	strictKeys           bool
+	formats              map[string]func([]byte) (map[string]interface{}, error)
+	lastResolved         map[string]interface{}
+	onLoad               []func()
+	onKeyChange          []keyChangeFunc
+	loadedSettings       map[string]interface{}
+	warnings             []string
 }
 
 // Option is an option function, which operates on an Amalgam instance.
@@ -36,6 +115,14 @@ func PreventConfigFlag(a *Amalgam) {
 	a.preventConfigFlag = true
 }
 
+// WithSetFlag adds a repeatable --set flag to the flagset, which accepts
+// key=value pairs overriding any other source of the config key.
+func WithSetFlag() func(*Amalgam) {
+	return func(a *Amalgam) {
+		a.setFlag = true
+	}
+}
+
 // WithEnvPrefix allows the caller to specify a prefix to use for populating
 // the config from environment variables.
 func WithEnvPrefix(prefix string) func(*Amalgam) {
@@ -44,6 +131,16 @@ func WithEnvPrefix(prefix string) func(*Amalgam) {
 	}
 }
 
+// WithDescriptionFunc composes the usage text of each flag from the field
+// name, the description from its tag, its default value and the name of its
+// environment variable, eg. to include the default and env var in the help
+// output.  By default, the usage is the description from the tag.
+func WithDescriptionFunc(fn func(field, desc string, def interface{}, env string) string) func(*Amalgam) {
+	return func(a *Amalgam) {
+		a.descriptionFunc = fn
+	}
+}
+
 // WithFlagSet allows the caller to specify the pflag FlagSet to use.
 func WithFlagSet(fs *pflag.FlagSet) func(*Amalgam) {
 	return func(a *Amalgam) {
@@ -51,6 +148,42 @@ func WithFlagSet(fs *pflag.FlagSet) func(*Amalgam) {
 	}
 }
 
+// WithFlagSetName has amalgam create its own flag set with the given name,
+// which is used in the usage output, instead of using pflag.CommandLine.  It
+// has no effect if WithFlagSet has been specified.
+func WithFlagSetName(name string) func(*Amalgam) {
+	return func(a *Amalgam) {
+		a.flagSetName = name
+	}
+}
+
+// WithName names the Amalgam, eg. `plugin config`, to tell apart the errors
+// from several of them.  The errors returned by New and the Amalgam's methods
+// (eg. LoadFile, Reload, Set and Finalize) are prefixed with the name,
+// wrapped in a NamedError.
+func WithName(name string) func(*Amalgam) {
+	return func(a *Amalgam) {
+		a.name = name
+	}
+}
+
+// WithArgs specifies the command-line arguments to parse when loading,
+// instead of os.Args[1:].  This is mostly useful for tests.
+func WithArgs(args []string) func(*Amalgam) {
+	return func(a *Amalgam) {
+		a.args = args
+	}
+}
+
+// WithUnknownFlags ignores unknown flags when parsing the command line,
+// rather than failing, eg. where other parts of a larger CLI define flags of
+// their own.
+func WithUnknownFlags() func(*Amalgam) {
+	return func(a *Amalgam) {
+		a.unknownFlags = true
+	}
+}
+
 // WithFlagNameFunc allows the caller to specify a function to determine
 // the flag name from the config key.
 func WithFlagNameFunc(fn func(string) string) func(*Amalgam) {
@@ -68,10 +201,121 @@ func WithDefaultConfigFile(configFile string) func(*Amalgam) {
 	}
 }
 
+// WithConfigDir sets the directory which relative config file paths (eg. from
+// the --config flag, or WithLayeredFiles) are resolved against, rather than
+// the working directory.  Absolute paths are used as they are.
+func WithConfigDir(dir string) func(*Amalgam) {
+	return func(a *Amalgam) {
+		a.configDir = dir
+	}
+}
+
+// configPath resolves a config file path against the config directory.
+func (a *Amalgam) configPath(name string) string {
+	if a.configDir == "" || name == "" || filepath.IsAbs(name) {
+		return name
+	}
+	return filepath.Join(a.configDir, name)
+}
+
+// WithConfigType specifies the format of the config (eg. "yaml"), for when it
+// can't be inferred from the config file extension, such as with Load.
+func WithConfigType(configType string) func(*Amalgam) {
+	return func(a *Amalgam) {
+		a.configType = configType
+	}
+}
+
 type fieldInfo struct {
 	value       reflect.Value
+	index       []int
 	description string
 	flagName    string
+	options     tagOptions
+	// The flag name prefix given by the tag of an enclosing struct field,
+	// and the path of that field.
+	flagPrefix  string
+	prefixField string
+}
+
+// defaultFlagName returns the flag name of a field not named by its tag, from
+// the flag name function, with the prefix given by an enclosing struct field
+// in place of that field's path.
+func (a *Amalgam) defaultFlagName(field string, info fieldInfo) string {
+	if info.flagPrefix == "" {
+		return a.flagNameFunc(field)
+	}
+	return info.flagPrefix + "-" + a.flagNameFunc(strings.TrimPrefix(field, info.prefixField+"."))
+}
+
+// excluded reports whether the field was tagged with `-`, and so isn't
+// populated from any source.
+func (f fieldInfo) excluded() bool {
+	return f.flagName == "-"
+}
+
+// tagOptions holds the option segments of an amalgam struct tag, keyed by
+// option name.  Options without a value map to an empty string.
+type tagOptions map[string]string
+
+// has reports whether the option was given in the tag.
+func (o tagOptions) has(name string) bool {
+	_, ok := o[name]
+	return ok
+}
+
+// knownTagOptions lists the option names recognised in an amalgam struct tag.
+// The first segment after the flag name that isn't a known option starts the
+// description.
+var knownTagOptions = map[string]bool{
+	"addrtype":          true,
+	"bareunit":          true,
+	"base":              true,
+	"default":           true,
+	"defaultenv":        true,
+	"deprecated":        true,
+	"durationunit":      true,
+	"env":               true,
+	"filewins":          true,
+	"group":             true,
+	"inline":            true,
+	"keyvalue":          true,
+	"layout":            true,
+	"mutexgroup":        true,
+	"oneof":             true,
+	"path":              true,
+	"remain":            true,
+	"requireexactlyone": true,
+	"requiredflag":      true,
+	"requiredwith":      true,
+	"requiredwithout":   true,
+	"secret":            true,
+	"short":             true,
+	"valuefile":         true,
+}
+
+// parseTag splits an amalgam struct tag into the flag name, the option
+// segments and the description.  The tag format is
+// `name[,option[=value]...][,description]`; the description may itself
+// contain commas.
+func parseTag(tag string) (flagName string, options tagOptions, description string) {
+	parts := strings.Split(tag, ",")
+	flagName = parts[0]
+	options = make(tagOptions)
+
+	for i := 1; i < len(parts); i++ {
+		key, value := parts[i], ""
+		if idx := strings.Index(key, "="); idx >= 0 {
+			key, value = key[:idx], key[idx+1:]
+		}
+		if !knownTagOptions[key] {
+			description = strings.Join(parts[i:], ",")
+			break
+		}
+		options[key] = value
+	}
+
+	return flagName, options, description
 }
 
 var defaultFlagNameFunc = func(name string) string {
@@ -104,31 +348,80 @@ func New(configObj interface{}, options ...Option) (*Amalgam, error) {
 	}
 
 	if a.flagSet == nil {
-		a.flagSet = pflag.CommandLine
+		if a.flagSetName != "" {
+			a.flagSet = pflag.NewFlagSet(a.flagSetName, pflag.ExitOnError)
+		} else {
+			a.flagSet = pflag.CommandLine
+		}
+	}
+	if a.unknownFlags {
+		a.flagSet.ParseErrorsWhitelist.UnknownFlags = true
 	}
+	// The flags already in the flag set (eg. those other packages add to
+	// pflag.CommandLine) aren't the Amalgam's own.
+	existing := make(map[string]bool)
+	a.flagSet.VisitAll(func(flag *pflag.Flag) {
+		existing[flag.Name] = true
+	})
 	if !a.preventConfigFlag {
 		a.flagSet.StringVarP(&a.configFile, "config", "c", a.configFile, "config file to use")
 	}
+	if a.setFlag {
+		a.flagSet.StringArrayVar(&a.setValues, "set", nil, "override a config key (key=value), may be repeated")
+	}
+
+	if a.strictEnvPrefix && a.envPrefix == "" {
+		return nil, a.named(errors.New("strict env prefix requires an env prefix"))
+	}
 
 	a.viper = viper.New()
 	if a.envPrefix != "" {
 		a.viper.SetEnvPrefix(a.envPrefix)
 	}
-	a.viper.AutomaticEnv()
+	if !a.explicitEnv {
+		a.viper.AutomaticEnv()
+	}
 	// This replacer should really be a function, to match the flag
 	// name function, but argh, it doesn't use an interface.
 	// This means that case changes or special characters in key names
-	// don't get separated with underscores.
-	replacer := strings.NewReplacer(".", "_", "-", "_")
-	a.viper.SetEnvKeyReplacer(replacer)
+	// don't get separated with underscores, so each field is also bound
+	// to its properly separated name in parse.
+	a.envKeyReplacer = strings.NewReplacer(".", "_", "-", "_")
+	a.viper.SetEnvKeyReplacer(a.envKeyReplacer)
 
 	if err := a.parse(a.configObj); err != nil {
-		return nil, err
+		return nil, a.named(err)
+	}
+	if err := a.checkTransforms(); err != nil {
+		return nil, a.named(err)
 	}
+	a.flagSet.VisitAll(func(flag *pflag.Flag) {
+		if !existing[flag.Name] {
+			a.ownFlags = append(a.ownFlags, flag.Name)
+		}
+	})
 
 	return a, nil
 }
 
+// FlagSet returns the flag set used by the Amalgam.
+func (a *Amalgam) FlagSet() *pflag.FlagSet {
+	return a.flagSet
+}
+
+// Changed reports whether the flag for a field (the dotted field path, eg.
+// `API.Timeout`) was explicitly set on the command line.  It returns false for
+// fields without a flag, and for all fields before the flags have been parsed.
+func (a *Amalgam) Changed(field string) bool {
+	_, info, ok := a.lookupField(field)
+	if !ok || info.excluded() {
+		return false
+	}
+
+	flag := a.flagSet.Lookup(info.flagName)
+	return flag != nil && flag.Changed
+}
+
 func (a *Amalgam) parse(configObj interface{}) error {
 	val := reflect.ValueOf(configObj)
 	if val.Kind() != reflect.Ptr {
@@ -139,96 +432,211 @@ func (a *Amalgam) parse(configObj interface{}) error {
 		return errors.New("config object must be addressable (a pointer)")
 	}
 
-	fm, err := structFieldTypes(val, "")
+	fm, err := structFieldTypes(val, "", a.decoderTagName)
 	if err != nil {
 		return err
 	}
 
-	fs := a.flagSet
-	tokenIP := net.ParseIP("127.0.0.1")
+	a.fields = fm
+	if a.strictDefinition {
+		if err := a.checkDefinition(); err != nil {
+			return err
+		}
+	}
+	if err := a.applyTagDefaults(); err != nil {
+		return err
+	}
+	if err := a.applyDefaultFuncs(); err != nil {
+		return err
+	}
+	if err := a.checkShorthands(); err != nil {
+		return err
+	}
 
 	for field, info := range fm {
+		if info.excluded() {
+			continue
+		}
+
+		// Flags with a shorthand are defined in a scratch flag set, then
+		// added to the flag set with the shorthand.
+		fs := a.flagSet
+		short := info.options["short"]
+		if short != "" {
+			fs = pflag.NewFlagSet(field, pflag.ContinueOnError)
+		}
+
+		// The flag and viper defaults come from def, which has any
+		// computed default in place of the field's value.
+		def := a.defaultInfo(field, info)
 		name := info.flagName
-		val := info.value.Interface()
-		a.viper.SetDefault(field, val)
+		val := def.value.Interface()
+		a.viper.SetDefault(field, copySlice(def.value))
+		if value, ok := envDefault(info); ok {
+			a.viper.SetDefault(field, value)
+		}
+		a.viper.BindEnv(field, a.envVarName(field))
 
 		if name == "" {
-			name = a.flagNameFunc(field)
-		} else if name == "-" {
-			continue
+			name = a.defaultFlagName(field, info)
+			info.flagName = name
+			fm[field] = info
+		}
+
+		usage := info.description
+		if a.descriptionFunc != nil {
+			usage = a.descriptionFunc(field, info.description, val, a.primaryEnvVarName(field))
+		}
+
+		format, err := inlineFormat(info)
+		if err != nil {
+			return fmt.Errorf("%s: %v", field, err)
 		}
 
-		switch info.value.Type() {
-		case reflect.TypeOf(tokenIP):
-			fs.IP(name, val.(net.IP), info.description)
-		case reflect.TypeOf(tokenIP.DefaultMask()):
-			fs.IPMask(name, val.(net.IPMask), info.description)
+		switch t := info.value.Type(); {
+		case format != "":
+			fs.String(name, inlineDefault(def), usage)
+		case t == ipType:
+			fs.IP(name, val.(net.IP), usage)
+		case t == ipMaskType:
+			fs.IPMask(name, val.(net.IPMask), usage)
+		case t == timeType:
+			fs.Var(newTimeValue(val.(time.Time), info.timeLayout()), name, usage)
+		case t == numberType:
+			fs.String(name, string(val.(json.Number)), usage)
+		case t == addrType:
+			if _, err := addrNetwork(info); err != nil {
+				// The field can still be set programmatically.
+				continue
+			}
+			def := ""
+			if val != nil {
+				def = val.(net.Addr).String()
+			}
+			fs.String(name, def, usage)
 		default:
+			if base, err := intBase(info); err != nil {
+				return fmt.Errorf("%s: %v", field, err)
+			} else if base != 0 {
+				fs.Var(newIntValue(def.value, base), name, usage)
+				break
+			}
+			if value := newFlagValue(def); value != nil {
+				fs.Var(value, name, usage)
+				break
+			}
+
 			switch info.value.Kind() {
 			case reflect.String:
-				fs.String(name, val.(string), info.description)
+				fs.String(name, val.(string), usage)
 			case reflect.Bool:
-				fs.Bool(name, val.(bool), info.description)
+				fs.Bool(name, val.(bool), usage)
 			case reflect.Int:
-				fs.Int(name, val.(int), info.description)
+				fs.Int(name, val.(int), usage)
 			case reflect.Int8:
-				fs.Int8(name, val.(int8), info.description)
+				fs.Int8(name, val.(int8), usage)
 			case reflect.Int16:
-				fs.Int16(name, val.(int16), info.description)
+				fs.Int16(name, val.(int16), usage)
 			case reflect.Int32:
-				fs.Int32(name, val.(int32), info.description)
+				fs.Int32(name, val.(int32), usage)
 			case reflect.Int64:
-				if info.value.Type() == reflect.TypeOf(time.Duration(10)) {
-					fs.Duration(name, val.(time.Duration), info.description)
+				if info.value.Type() == durationType {
+					unit, err := a.durationUnit(info)
+					if err != nil {
+						return fmt.Errorf("%s: %v", field, err)
+					}
+					if unit != 0 {
+						fs.Var(newDurationValue(val.(time.Duration), unit), name, usage)
+					} else {
+						fs.Duration(name, val.(time.Duration), usage)
+					}
 				} else {
-					fs.Int64(name, val.(int64), info.description)
+					fs.Int64(name, val.(int64), usage)
 				}
 			case reflect.Uint:
-				fs.Uint(name, val.(uint), info.description)
+				fs.Uint(name, val.(uint), usage)
 			case reflect.Uint8:
-				fs.Uint8(name, val.(uint8), info.description)
+				fs.Uint8(name, val.(uint8), usage)
 			case reflect.Uint16:
-				fs.Uint16(name, val.(uint16), info.description)
+				fs.Uint16(name, val.(uint16), usage)
 			case reflect.Uint32:
-				fs.Uint32(name, val.(uint32), info.description)
+				fs.Uint32(name, val.(uint32), usage)
 			case reflect.Uint64:
-				fs.Uint64(name, val.(uint64), info.description)
+				fs.Uint64(name, val.(uint64), usage)
 			case reflect.Float32:
-				fs.Float32(name, val.(float32), info.description)
+				fs.Float32(name, val.(float32), usage)
 			case reflect.Float64:
-				fs.Float64(name, val.(float64), info.description)
+				fs.Float64(name, val.(float64), usage)
 			case reflect.Slice:
+				// Slices of other types (eg. structs) get no flag, but are
+				// still decoded from the config file.
 				elem := info.value.Type().Elem()
 				switch elem {
-				case reflect.TypeOf(net.ParseIP("127.0.0.1")):
-					fs.IPSlice(name, info.value.Interface().([]net.IP), info.description)
+				case ipType:
+					fs.IPSlice(name, val.([]net.IP), usage)
+				case ipMaskType:
+					fs.Var(newIPMaskSliceValue(val.([]net.IPMask)), name, usage)
+				case timeType:
+					fs.Var(newTimeSliceValue(val.([]time.Time), info.timeLayout()), name, usage)
 				default:
+					if isValueType(elem) {
+						fs.Var(newTextSliceValue(def.value), name, usage)
+						break
+					}
 					switch elem.Kind() {
 					case reflect.String:
-						fs.StringSlice(name, val.([]string), info.description)
+						if info.options.has("keyvalue") {
+							fs.Var(newPairSliceValue(val.([]string)), name, usage)
+						} else {
+							fs.StringSlice(name, val.([]string), usage)
+						}
 					case reflect.Bool:
-						fs.BoolSlice(name, val.([]bool), info.description)
+						fs.BoolSlice(name, val.([]bool), usage)
 					case reflect.Int:
-						fs.IntSlice(name, val.([]int), info.description)
+						fs.IntSlice(name, val.([]int), usage)
 					case reflect.Int64:
-						if elem == reflect.TypeOf(time.Duration(10)) {
-							fs.DurationSlice(name, val.([]time.Duration), info.description)
+						if elem == durationType {
+							fs.DurationSlice(name, val.([]time.Duration), usage)
 						}
 					case reflect.Uint:
-						fs.UintSlice(name, val.([]uint), info.description)
+						fs.UintSlice(name, val.([]uint), usage)
 					case reflect.Uint8:
 						// this is probably a []byte, so let's treat it as such
-						if elem == reflect.TypeOf(net.ParseIP("127.0.0.1")) {
-							fs.IP(name, val.(net.IP), info.description)
+						if elem == ipType {
+							fs.IP(name, val.(net.IP), usage)
 						} else {
-							fs.BytesHex(name, val.([]byte), info.description)
+							fs.BytesHex(name, val.([]byte), usage)
 						}
 					}
 				}
 			}
 		}
 
-		if flag := fs.Lookup(name); flag != nil {
+		flag := fs.Lookup(name)
+		if flag != nil && short != "" {
+			flag.Shorthand = short
+			a.flagSet.AddFlag(flag)
+		}
+
+		if group := info.options["group"]; flag != nil && group != "" {
+			fs.SetAnnotation(name, FlagGroupAnnotation, []string{group})
+		}
+
+		// Only the default shown in the help is in the display unit, so
+		// the flag's value is still parsed exactly.
+		if unit := info.options["durationunit"]; flag != nil && unit != "" && info.value.Type() == durationType {
+			def, err := formatDurationIn(val.(time.Duration), unit)
+			if err != nil {
+				return fmt.Errorf("%s: %v", field, err)
+			}
+			if def != "0"+unit {
+				flag.DefValue = def
+			}
+		}
+
+		// Fields where the config file wins get their flag value as a
+		// default instead, in resolve.
+		if flag != nil && !info.options.has("filewins") {
 			a.viper.BindPFlag(field, flag)
 		}
 	}
@@ -238,71 +646,581 @@ func (a *Amalgam) parse(configObj interface{}) error {
 
 // LoadFile hydrates the config from the config file.  This is either the value
 // of the configFile property, or a value specified by the --config flag (if allowed).
+// A config file of `-` reads the config from stdin.
 func (a *Amalgam) LoadFile() error {
-	if !a.flagSet.Parsed() {
-		a.flagSet.Parse(os.Args[1:])
+	return a.named(a.loadFile())
+}
+
+// loadFile does the work of LoadFile.
+func (a *Amalgam) loadFile() error {
+	if err := a.parseFlags(); err != nil {
+		return err
+	}
+
+	if a.configFile == "" && a.baseFile != "" {
+		return a.loadLayered()
 	}
 
 	// If no config file is specified, load from a blank file
 	// to allow flags to update config object.
 	if a.configFile == "" {
-		return a.Load(bytes.NewReader(nil))
+		return a.load(bytes.NewReader(nil), "")
 	}
 
-	a.viper.SetConfigFile(a.configFile)
+	if a.configFile == "-" {
+		return a.loadStdin()
+	}
 
-	if err := a.viper.ReadInConfig(); err != nil {
+	name := a.configPath(a.configFile)
+	f, err := os.Open(name)
+	if err != nil {
 		return err
 	}
+	defer f.Close()
 
-	if err := a.viper.Unmarshal(a.configObj); err != nil {
+	if err := a.loadDocs(configDoc{r: f, format: a.formatFor(name), name: name}); err != nil {
 		return err
 	}
+	a.loadedFile, a.loadedLayered = name, false
 
 	return nil
 }
 
-// Load hydrates the config from an io.Reader.
+// Load hydrates the config from an io.Reader.  The format is the one given by
+// WithConfigType, or else inferred from the config file extension.
 func (a *Amalgam) Load(r io.Reader) error {
-	if !a.flagSet.Parsed() {
-		a.flagSet.Parse(os.Args[1:])
+	return a.named(a.load(r, a.formatFor(a.configFile)))
+}
+
+// parseFlags parses the command-line arguments into the flag set, unless
+// they've already been parsed.  Unknown flags are only an error without
+// WithUnknownFlags.
+func (a *Amalgam) parseFlags() error {
+	if a.flagSet.Parsed() {
+		return nil
+	}
+	return a.flagSet.Parse(a.cmdArgs())
+}
+
+// formatFor returns the format of the named config file.
+func (a *Amalgam) formatFor(name string) string {
+	if a.configType != "" {
+		return a.configType
 	}
+	return strings.TrimPrefix(filepath.Ext(strings.TrimSuffix(name, gzipExt)), ".")
+}
 
-	if err := a.viper.ReadConfig(r); err != nil {
+// cmdArgs returns the command-line arguments to parse, from WithArgs or
+// os.Args.
+func (a *Amalgam) cmdArgs() []string {
+	if a.args != nil {
+		return a.args
+	}
+	return os.Args[1:]
+}
+
+// configDoc is a config document to be read in the format from r.  The name
+// is the path of the file it was read from, if any, and if key is set, only
+// the value at that key is used.  If root is set, the value at that key is
+// used as the whole document.  The size is a hint of its size, if known.
+// Alternatively, the document may be given as a settings map, or be the
+// previously loaded config.
+type configDoc struct {
+	r        io.Reader
+	format   string
+	size     int64
+	name     string
+	key      string
+	root     string
+	settings map[string]interface{}
+	previous bool
+}
+
+// load reads config in the format from r, then resolves it into the config
+// object.  An empty format reads nothing, leaving only the other sources.
+func (a *Amalgam) load(r io.Reader, format string) error {
+	return a.loadDocs(configDoc{r: r, format: format})
+}
+
+// loadDocs reads and merges the config documents in order, with later
+// documents taking precedence, then resolves them into the config object.
+func (a *Amalgam) loadDocs(docs ...configDoc) error {
+	if err := a.parseFlags(); err != nil {
 		return err
 	}
 
-	if err := a.viper.Unmarshal(a.configObj); err != nil {
+	a.mu.Lock()
+	err := a.loadLocked(docs...)
+	a.mu.Unlock()
+	if err != nil {
 		return err
 	}
 
+	a.notifyLoad()
 	return nil
 }
 
-func structFieldTypes(val reflect.Value, prefix string) (fieldMap, error) {
+// loadLocked does the work of loadDocs, with the write lock held.
+func (a *Amalgam) loadLocked(docs ...configDoc) error {
+	if a.frozen {
+		return ErrFrozen
+	}
+
+	settings := make(map[string]interface{})
+	secretSources := make(map[string]string)
+	var raw map[string]interface{}
+	// The selected profile must be in one of the documents, unless only
+	// the flags are loaded.  The previously loaded config was checked when
+	// it was loaded.
+	profileFound := a.profile == "" || a.flagsOnly
+	for _, doc := range docs {
+		includes := a.includes && doc.name != "" && doc.key == ""
+		docSettings, docRaw, err := a.readDoc(doc)
+		if err == nil && includes {
+			docSettings, docRaw, err = a.readIncludes(doc.name, docSettings, docRaw, secretSources, nil)
+		}
+		if err == nil && !doc.previous {
+			var found bool
+			docSettings, docRaw, found = a.applyProfile(docSettings, docRaw)
+			profileFound = profileFound || found
+		}
+		if err != nil {
+			if doc.name != "" {
+				return fmt.Errorf("%s: %v", doc.name, err)
+			}
+			return err
+		}
+
+		settings = a.mergeDocs(settings, docSettings)
+		if docRaw != nil {
+			raw = a.mergeDocs(raw, docRaw)
+		}
+		if doc.previous {
+			for field, name := range a.secretSources {
+				secretSources[field] = name
+			}
+		} else if doc.name != "" && !includes {
+			a.recordSecretSources(secretSources, doc.name, docSettings)
+		}
+		profileFound = profileFound || doc.previous
+	}
+	if !profileFound {
+		return fmt.Errorf("unknown profile %q", a.profile)
+	}
+	a.fileSettings = settings
+	a.rawFileSettings = raw
+	a.secretSources = secretSources
+	if a.strictKeys {
+		if err := a.checkUnknownKeys(); err != nil {
+			return err
+		}
+	}
+
+	// viper can't unset a previous config type, so read an empty document in
+	// a format which accepts one, then add the merged settings.
+	a.viper.SetConfigType("yaml")
+	if err := a.viper.ReadConfig(bytes.NewReader(nil)); err != nil {
+		return err
+	}
+	if err := a.viper.MergeConfigMap(mergeSettings(nil, settings)); err != nil {
+		return err
+	}
+
+	return a.resolve()
+}
+
+// readDoc reads and migrates a config document, returning its settings, and
+// its raw settings if they're needed (see rawFileSettings).
+func (a *Amalgam) readDoc(doc configDoc) (settings, raw map[string]interface{}, err error) {
+	switch {
+	case doc.previous:
+		if a.fileSettings == nil {
+			return make(map[string]interface{}), a.rawFileSettings, nil
+		}
+		return a.fileSettings, a.rawFileSettings, nil
+	case doc.settings != nil:
+		if a.caseSensitiveKeys || a.hasNumberFields() {
+			raw = doc.settings
+		}
+		return lowercaseKeys(doc.settings), raw, nil
+	}
+
+	r, format := doc.r, doc.format
+	unmarshal := a.formats[strings.ToLower(format)]
+	if format == "" {
+		r, format = bytes.NewReader(nil), "yaml"
+	} else if unmarshal == nil && !isSupportedFormat(format) {
+		return nil, nil, viper.UnsupportedConfigError(format)
+	}
+
+	// The document is streamed into viper, which reads it into its own
+	// buffer, unless it's needed whole: for a registered format, or to parse
+	// the raw settings from (see below).
+	r, err = gunzip(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	var data []byte
+	if unmarshal != nil || a.caseSensitiveKeys || a.hasNumberFields() && strings.EqualFold(format, "json") {
+		if data, err = readAll(r, doc.size); err != nil {
+			return nil, nil, err
+		}
+		r = bytes.NewReader(data)
+	}
+	var parsed, custom map[string]interface{}
+	if unmarshal != nil {
+		if custom, err = unmarshal(data); err != nil {
+			return nil, nil, err
+		}
+		custom = stringKeyMap(custom)
+		parsed = lowercaseKeys(custom)
+	} else {
+		src := viper.New()
+		src.SetConfigType(format)
+		er := &errReader{r: r}
+		err := src.ReadConfig(er)
+		if er.err != nil {
+			return nil, nil, er.err
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		parsed = src.AllSettings()
+	}
+	if doc.root != "" {
+		if parsed, err = rootAt(doc.root, parsed); err != nil {
+			return nil, nil, err
+		}
+		if custom != nil {
+			custom, _ = rootAt(doc.root, custom)
+		}
+	}
+	settings, migrated, err := a.migrate(parsed)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// The raw documents are also needed for json.Number fields, as viper
+	// parses JSON numbers as float64.
+	if a.caseSensitiveKeys || a.hasNumberFields() {
+		// Migrations apply to the lowercased settings, so these are used
+		// for migrated documents.
+		raw = settings
+		if !migrated && unmarshal != nil {
+			raw = custom
+		} else if !migrated && (a.caseSensitiveKeys || strings.EqualFold(format, "json")) {
+			if raw, err = parseRawConfig(format, data); err != nil {
+				return nil, nil, err
+			}
+			if doc.root != "" {
+				raw, _ = rootAt(doc.root, raw)
+			}
+		}
+	}
+
+	if a.keyRewriter != nil {
+		settings = lowercaseKeys(a.rewriteKeys(settings, ""))
+		if raw != nil {
+			raw = a.rewriteKeys(raw, "")
+		}
+	}
+
+	if doc.key != "" {
+		return subtreeAt(doc.key, settings, raw)
+	}
+	return settings, raw, nil
+}
+
+func isSupportedFormat(format string) bool {
+	for _, ext := range viper.SupportedExts {
+		if strings.EqualFold(ext, format) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolve applies the --set overrides, then checks and unmarshals the
+// resolved settings into the config object, and validates the result.
+func (a *Amalgam) resolve() error {
+	lists := make(map[string]interface{})
+	for _, pair := range a.setValues {
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return fmt.Errorf("invalid --set value %q, expected key=value", pair)
+		}
+		if err := a.setValue(parts[0], parts[1], lists); err != nil {
+			return err
+		}
+	}
+	a.setFileWinsDefaults()
+
+	fileValues, err := a.readValueFiles()
+	if err != nil {
+		return err
+	}
+	if err := a.readSecretsDir(fileValues); err != nil {
+		return err
+	}
+	a.fileValues = fileValues
+	a.consumedEnv = a.consumedEnvVars()
+
+	a.warnings = a.deprecationWarnings()
+
+	if a.typeChecks {
+		if err := a.checkTypes(); err != nil {
+			return err
+		}
+	}
+	if !a.deferValidation {
+		if err := a.checkRequiredFlags(); err != nil {
+			return err
+		}
+	}
+	if err := a.checkSecretFiles(); err != nil {
+		return err
+	}
+
+	if err := a.unmarshal(); err != nil {
+		return err
+	}
+
+	if a.deferValidation {
+		return nil
+	}
+	if err := a.validate(); err != nil {
+		return err
+	}
+	return a.validateConfig()
+}
+
+// unmarshal decodes the resolved settings into the config object.  The
+// settings for excluded fields are removed first, so that they are left
+// untouched, and the remaining settings are converted per field.
+func (a *Amalgam) unmarshal() error {
+	settings := a.viper.AllSettings()
+	resolved := make(map[string]interface{}, len(a.fields))
+	unchanged := make(map[string]bool)
+	for field, info := range a.fields {
+		path := keyPath(field)
+		if info.excluded() {
+			deletePath(settings, path)
+			continue
+		}
+
+		raw, ok := lookupPath(settings, path)
+		if value, exact := a.exactEnvValue(field); exact {
+			raw, ok = value, true
+		}
+		if value, file := a.fileValues[field]; file {
+			raw, ok = value, true
+		}
+		if !ok {
+			continue
+		}
+		raw, err := a.splitEnvSlice(field, info, raw)
+		if err != nil {
+			return fmt.Errorf("%s: %v", field, err)
+		}
+		raw = a.fileNumber(field, info, raw)
+		value, err := a.convertField(info, raw)
+		if err != nil {
+			return fmt.Errorf("%s: %v", field, err)
+		}
+
+		prev, seen := a.lastResolved[field]
+		resolved[field] = value
+		if a.changedOnly && seen && reflect.DeepEqual(prev, value) {
+			unchanged[field] = true
+			deletePath(settings, path)
+			continue
+		}
+		setPath(settings, path, value)
+
+		// The decoder overwrites existing slices element by element,
+		// leaving any extra elements, and decodes into the existing value
+		// of an interface, so start these from scratch.
+		if v := a.fieldValue(info); (v.Kind() == reflect.Slice || v.Kind() == reflect.Interface) && v.CanSet() {
+			v.Set(reflect.Zero(v.Type()))
+		}
+	}
+
+	var overlay map[string]interface{}
+	if a.caseSensitiveKeys {
+		overlay = a.caseSensitiveOverlay(settings, unchanged)
+	}
+
+	decoder, err := mapstructure.NewDecoder(a.decoderConfig(a.configObj))
+	if err != nil {
+		return err
+	}
+	if err := decoder.Decode(settings); err != nil {
+		return err
+	}
+	if len(overlay) > 0 {
+		if err := decoder.Decode(overlay); err != nil {
+			return err
+		}
+	}
+
+	a.lastResolved = resolved
+	a.collectRemain(unchanged)
+	if a.interpolateKeys {
+		if err := a.interpolate(unchanged); err != nil {
+			return err
+		}
+	}
+	if a.expandEnv && !a.flagsOnly {
+		if err := a.expandEnvVars(unchanged); err != nil {
+			return err
+		}
+	}
+	return a.applyTransforms(unchanged)
+}
+
+// fieldValue returns the current value of a field in the config object,
+// following pointers.  Unmarshalling may replace the pointers in the config
+// object, so this should be used rather than the value from the field map
+// once the config has been loaded.
+func (a *Amalgam) fieldValue(info fieldInfo) reflect.Value {
+	v := reflect.ValueOf(a.configObj)
+	for _, i := range info.index {
+		v = indirect(v).Field(i)
+	}
+	return indirect(v)
+}
+
+// copySlice returns the value, copying it if it's a slice so that the copy
+// doesn't share the backing array with the config object.  This keeps the
+// defaults intact when the config object is updated.
+func copySlice(v reflect.Value) interface{} {
+	if v.Kind() != reflect.Slice || v.IsNil() {
+		return v.Interface()
+	}
+
+	c := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+	reflect.Copy(c, v)
+	return c.Interface()
+}
+
+// indirect follows pointers until reaching a non-pointer value.  It returns
+// the zero Value if a nil pointer is reached.
+func indirect(v reflect.Value) reflect.Value {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+// inFile reports whether the config file provided a value for the field.
+func (a *Amalgam) inFile(field string) bool {
+	_, ok := lookupPath(a.fileSettings, keyPath(field))
+	return ok
+}
+
+// fromFile reports whether the config file provided the resolved value for
+// the field, ie. it's in the file and not overridden by a flag, the
+// environment, a value file, the secrets directory, --set or Set.
+func (a *Amalgam) fromFile(field string) bool {
+	_, file := a.fileValues[field]
+	return a.inFile(field) && !a.inEnv(field) && !file && !a.flagOrSetValue(field)
+}
+
+// flagOrSetValue reports whether the field's value was given by its flag, by
+// --set or by Set, which take precedence over the environment and the config
+// file.
+func (a *Amalgam) flagOrSetValue(field string) bool {
+	if a.setFields[field] {
+		return true
+	}
+	info := a.fields[field]
+	if flag := a.flagSet.Lookup(info.flagName); flag != nil && flag.Changed && !info.options.has("filewins") {
+		return true
+	}
+	for _, pair := range a.setValues {
+		if key := strings.SplitN(pair, "=", 2)[0]; strings.EqualFold(key, field) {
+			return true
+		}
+	}
+	return false
+}
+
+// setFileWinsDefaults sets the values of the flags given for fields tagged
+// with `filewins` as their defaults, so that the config file and environment
+// take precedence over them.
+func (a *Amalgam) setFileWinsDefaults() {
+	for field, info := range a.fields {
+		if info.excluded() || !info.options.has("filewins") {
+			continue
+		}
+		if flag := a.flagSet.Lookup(info.flagName); flag != nil && flag.Changed {
+			a.viper.SetDefault(field, flag.Value.String())
+		}
+	}
+}
+
+// maxPointerDepth bounds the levels of pointers followed for a field.
+const maxPointerDepth = 8
+
+func structFieldTypes(val reflect.Value, prefix, keyTag string) (fieldMap, error) {
+	return walkStruct(val, prefix, keyTag, nil, make(map[reflect.Type]bool))
+}
+
+// walkStruct builds the field map for a struct value.  Nil pointers are
+// allocated, so that every field has a value to use as its default.  The
+// struct types being walked are tracked in parents, to catch recursive types.
+// The fields are named by the keyTag struct tag, if given, as they are when
+// decoding.
+func walkStruct(val reflect.Value, prefix, keyTag string, index []int, parents map[reflect.Type]bool) (fieldMap, error) {
 	types := make(fieldMap)
 
 	if val.Kind() != reflect.Struct {
 		return nil, errors.New("reflected object must be a struct value")
 	}
+	parents[val.Type()] = true
+	defer delete(parents, val.Type())
 
 	for i := 0; i < val.NumField(); i++ {
 		structField := val.Type().Field(i)
-		tagParts := strings.SplitN(structField.Tag.Get(tagName), ",", 2)
-		flagName := tagParts[0]
-		description := ""
-		if len(tagParts) > 1 {
-			description = tagParts[1]
+		flagName, options, description := parseTag(structField.Tag.Get(tagName))
+
+		fieldName := structField.Name
+		if keyTag != "" {
+			key := strings.Split(structField.Tag.Get(keyTag), ",")[0]
+			if key == "-" {
+				continue
+			}
+			if key != "" {
+				fieldName = key
+			}
 		}
+		if prefix != "" {
+			fieldName = prefix + "." + fieldName
+		}
+
 		fieldValue := val.Field(i)
-		if fieldValue.Kind() == reflect.Ptr {
+		for depth := 0; fieldValue.Kind() == reflect.Ptr; depth++ {
+			if depth == maxPointerDepth {
+				return nil, fmt.Errorf("%s: too many levels of pointers", fieldName)
+			}
+			if fieldValue.IsNil() {
+				if !fieldValue.CanSet() {
+					break
+				}
+				fieldValue.Set(reflect.New(fieldValue.Type().Elem()))
+			}
 			fieldValue = fieldValue.Elem()
 		}
 
 		fieldInfo := fieldInfo{
 			value:       fieldValue,
+			index:       append(append([]int(nil), index...), i),
 			description: description,
 			flagName:    flagName,
+			options:     options,
 		}
 
 		if !fieldInfo.value.CanInterface() {
@@ -311,17 +1229,22 @@ func structFieldTypes(val reflect.Value, prefix string) (fieldMap, error) {
 			continue
 		}
 
-		fieldName := structField.Name
-		if prefix != "" {
-			fieldName = prefix + "." + fieldName
-		}
-
-		if fieldValue.Type().Kind() == reflect.Struct {
-			fieldTypes, err := structFieldTypes(fieldValue, fieldName)
+		if fieldValue.Type().Kind() == reflect.Struct && fieldValue.Type() != timeType && !isValueType(fieldValue.Type()) &&
+			flagName != "-" && !options.has("inline") {
+			if parents[fieldValue.Type()] {
+				return nil, fmt.Errorf("%s: recursive struct type %s is not supported", fieldName, fieldValue.Type())
+			}
+			fieldTypes, err := walkStruct(fieldValue, fieldName, keyTag, fieldInfo.index, parents)
 			if err != nil {
 				return nil, err
 			}
 			for name, ft := range fieldTypes {
+				// A flag name on the struct field prefixes the flags of
+				// its fields, unless they're named themselves, or by a
+				// nested struct field.
+				if flagName != "" && ft.flagName == "" && ft.flagPrefix == "" {
+					ft.flagPrefix, ft.prefixField = flagName, fieldName
+				}
 				types[name] = ft
 			}
 		} else {
@@ -0,0 +1,368 @@
+package amalgam
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/spf13/pflag"
+)
+
+// newTestAmalgam creates an Amalgam for the config with its own flag set,
+// which parses args rather than os.Args.  The config format defaults to YAML.
+func newTestAmalgam(t testing.TB, config interface{}, args []string, options ...Option) *Amalgam {
+	t.Helper()
+	fs := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	if args == nil {
+		args = []string{}
+	}
+	options = append([]Option{WithFlagSet(fs), WithArgs(args), WithConfigType("yaml")}, options...)
+	a, err := New(config, options...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a
+}
+
+// loadYAML loads the YAML document into the Amalgam, failing the test on an
+// error.
+func loadYAML(t testing.TB, a *Amalgam, doc string) {
+	t.Helper()
+	if err := a.Load(strings.NewReader(doc)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// tempDir creates a temporary directory, returning it and a function which
+// removes it.
+func tempDir(t testing.TB) (string, func()) {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "amalgam")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir, func() { os.RemoveAll(dir) }
+}
+
+// writeFile writes a file in the directory, creating any parent directories,
+// and returns its path.
+func writeFile(t testing.TB, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// setEnv sets an environment variable, returning a function which restores
+// its previous value.
+func setEnv(key, value string) func() {
+	old, ok := os.LookupEnv(key)
+	os.Setenv(key, value)
+	return func() {
+		if ok {
+			os.Setenv(key, old)
+		} else {
+			os.Unsetenv(key)
+		}
+	}
+}
+
+// assertError fails the test unless err is an error containing want.
+func assertError(t testing.TB, err error, want string) {
+	t.Helper()
+	if err == nil {
+		t.Fatalf("got no error, want one containing %q", want)
+	}
+	if !strings.Contains(err.Error(), want) {
+		t.Fatalf("got error %q, want one containing %q", err, want)
+	}
+}
+
+func TestSetFlagOverridesNestedKey(t *testing.T) {
+	var config struct {
+		Name     string
+		Database struct {
+			Host     string
+			MaxConns int
+		}
+	}
+	a := newTestAmalgam(t, &config, []string{"--database-max-conns", "5", "--set", "database.maxconns=10", "--set", "name=from-set"}, WithSetFlag())
+	loadYAML(t, a, "name: from-file\ndatabase:\n  host: db.local\n  maxconns: 2\n")
+
+	if config.Database.MaxConns != 10 {
+		t.Errorf("Database.MaxConns = %d, want 10", config.Database.MaxConns)
+	}
+	if config.Database.Host != "db.local" {
+		t.Errorf("Database.Host = %q, want the file's value", config.Database.Host)
+	}
+	if config.Name != "from-set" {
+		t.Errorf("Name = %q, want from-set", config.Name)
+	}
+}
+
+func TestSetFlagInvalidPair(t *testing.T) {
+	var config struct {
+		Name string
+	}
+	a := newTestAmalgam(t, &config, []string{"--set", "name"}, WithSetFlag())
+	assertError(t, a.Load(strings.NewReader("")), `invalid --set value "name"`)
+}
+
+func TestDashTagExcludesField(t *testing.T) {
+	type internal struct {
+		Token string
+	}
+	var config struct {
+		Name     string
+		Secret   string   `amalgam:"-"`
+		Internal internal `amalgam:"-"`
+	}
+	config.Secret = "kept"
+	config.Internal.Token = "kept"
+	defer setEnv("SECRET", "from-env")()
+	defer setEnv("INTERNAL_TOKEN", "from-env")()
+
+	a := newTestAmalgam(t, &config, nil)
+	for _, name := range []string{"secret", "internal", "internal-token"} {
+		if a.flagSet.Lookup(name) != nil {
+			t.Errorf("got a --%s flag for an excluded field", name)
+		}
+	}
+	loadYAML(t, a, "name: svc\nsecret: from-file\ninternal:\n  token: from-file\n")
+
+	if config.Secret != "kept" {
+		t.Errorf("Secret = %q, want it untouched", config.Secret)
+	}
+	if config.Internal.Token != "kept" {
+		t.Errorf("Internal.Token = %q, want it untouched", config.Internal.Token)
+	}
+	if config.Name != "svc" {
+		t.Errorf("Name = %q, want svc", config.Name)
+	}
+}
+
+func TestDoublePointerField(t *testing.T) {
+	var config struct {
+		Name **string
+		Port ***int
+	}
+	a := newTestAmalgam(t, &config, []string{"--name", "from-flag"})
+	loadYAML(t, a, "port: 8080\n")
+
+	if config.Name == nil || *config.Name == nil || **config.Name != "from-flag" {
+		t.Errorf("Name not set through the pointer chain")
+	}
+	if config.Port == nil || *config.Port == nil || **config.Port == nil || ***config.Port != 8080 {
+		t.Errorf("Port not set through the pointer chain")
+	}
+}
+
+func TestWithArgsIsolated(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	os.Args = []string{"cmd", "--name", "from-os-args"}
+
+	var first, second struct {
+		Name string
+	}
+	a := newTestAmalgam(t, &first, []string{"--name", "first"})
+	b := newTestAmalgam(t, &second, []string{"--name", "second"})
+	loadYAML(t, a, "")
+	loadYAML(t, b, "")
+
+	if first.Name != "first" || second.Name != "second" {
+		t.Errorf("got %q and %q, want each Amalgam to parse its own args", first.Name, second.Name)
+	}
+}
+
+func TestWithArgsEmpty(t *testing.T) {
+	oldArgs := os.Args
+	defer func() { os.Args = oldArgs }()
+	os.Args = []string{"cmd", "--name", "from-os-args"}
+
+	var config struct {
+		Name string
+	}
+	a := newTestAmalgam(t, &config, []string{})
+	loadYAML(t, a, "name: from-file\n")
+	if config.Name != "from-file" {
+		t.Errorf("Name = %q, want os.Args ignored", config.Name)
+	}
+}
+
+func TestSliceDefaultsPreserved(t *testing.T) {
+	var config struct {
+		Hosts  []string
+		Ports  []int
+		Quoted []string
+	}
+	config.Hosts = []string{"a", "b"}
+	config.Ports = []int{80, 443}
+	config.Quoted = []string{"x,y", "[z]"}
+
+	a := newTestAmalgam(t, &config, nil)
+	loadYAML(t, a, "")
+
+	if !reflect.DeepEqual(config.Hosts, []string{"a", "b"}) {
+		t.Errorf("Hosts = %q, want the struct default", config.Hosts)
+	}
+	if !reflect.DeepEqual(config.Ports, []int{80, 443}) {
+		t.Errorf("Ports = %v, want the struct default", config.Ports)
+	}
+	if !reflect.DeepEqual(config.Quoted, []string{"x,y", "[z]"}) {
+		t.Errorf("Quoted = %q, want the struct default", config.Quoted)
+	}
+}
+
+func TestUnknownFlags(t *testing.T) {
+	var config struct {
+		Name string
+	}
+	args := []string{"--verbose", "--name", "svc", "--other=1"}
+	a := newTestAmalgam(t, &config, args)
+	assertError(t, a.Load(strings.NewReader("")), "unknown flag: --verbose")
+
+	a = newTestAmalgam(t, &config, args, WithUnknownFlags())
+	loadYAML(t, a, "")
+	if config.Name != "svc" {
+		t.Errorf("Name = %q, want amalgam's own flags still parsed", config.Name)
+	}
+}
+
+func TestDescriptionFunc(t *testing.T) {
+	var config struct {
+		Port int    `amalgam:",the port to listen on"`
+		Name string `amalgam:",the service name"`
+	}
+	config.Port = 8080
+	describe := func(field, desc string, def interface{}, env string) string {
+		return fmt.Sprintf("%s (field %s, default %v, env %s)", desc, field, def, env)
+	}
+	a := newTestAmalgam(t, &config, nil, WithEnvPrefix("MYAPP"), WithDescriptionFunc(describe))
+
+	if got, want := a.flagSet.Lookup("port").Usage, "the port to listen on (field Port, default 8080, env MYAPP_PORT)"; got != want {
+		t.Errorf("port usage = %q, want %q", got, want)
+	}
+
+	a = newTestAmalgam(t, &config, nil)
+	if got, want := a.flagSet.Lookup("name").Usage, "the service name"; got != want {
+		t.Errorf("name usage = %q, want the raw description", got)
+	}
+}
+
+func TestLoadFlagParseErrors(t *testing.T) {
+	var config struct {
+		Port int
+	}
+	for name, load := range map[string]func(a *Amalgam) error{
+		"Load":          func(a *Amalgam) error { return a.Load(strings.NewReader("")) },
+		"LoadFile":      func(a *Amalgam) error { return a.LoadFile() },
+		"LoadFlagsOnly": func(a *Amalgam) error { return a.LoadFlagsOnly() },
+	} {
+		a := newTestAmalgam(t, &config, []string{"--port", "eighty"})
+		if err := load(a); err == nil || !strings.Contains(err.Error(), `invalid argument "eighty"`) {
+			t.Errorf("%s: got error %v, want the flag parse error", name, err)
+		}
+	}
+}
+
+func TestFileWins(t *testing.T) {
+	var config struct {
+		Region string `amalgam:",filewins"`
+		Name   string
+	}
+	args := []string{"--region", "flag-region", "--name", "flag-name"}
+	a := newTestAmalgam(t, &config, args)
+	loadYAML(t, a, "region: file-region\nname: file-name\n")
+
+	if config.Region != "file-region" {
+		t.Errorf("Region = %q, want the file over the flag", config.Region)
+	}
+	if config.Name != "flag-name" {
+		t.Errorf("Name = %q, want the flag over the file", config.Name)
+	}
+
+	// The flag is still used when the file doesn't give the field.
+	a = newTestAmalgam(t, &config, args)
+	loadYAML(t, a, "")
+	if config.Region != "flag-region" {
+		t.Errorf("Region = %q, want the flag as a fallback", config.Region)
+	}
+}
+
+func TestConfigDir(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+	writeFile(t, dir, "examples/app.yaml", "name: relative\n")
+	abs := writeFile(t, dir, "other/app.yaml", "name: absolute\n")
+
+	for path, want := range map[string]string{
+		filepath.Join("examples", "app.yaml"): "relative",
+		abs:                                   "absolute",
+	} {
+		var config struct {
+			Name string
+		}
+		a := newTestAmalgam(t, &config, []string{"--config", path}, WithConfigType(""), WithConfigDir(dir))
+		if err := a.LoadFile(); err != nil {
+			t.Fatal(err)
+		}
+		if config.Name != want {
+			t.Errorf("--config %s: Name = %q, want %q", path, config.Name, want)
+		}
+	}
+}
+
+func TestNestedStructFlagPrefix(t *testing.T) {
+	var config struct {
+		Database struct {
+			Host     string
+			MaxConns int
+			Port     int `amalgam:"database-port"`
+		} `amalgam:"db"`
+		Cache struct {
+			Host string
+		}
+	}
+	a := newTestAmalgam(t, &config, []string{"--db-host", "db.local", "--db-max-conns", "10", "--database-port", "5432", "--cache-host", "cache.local"})
+	loadYAML(t, a, "")
+
+	if a.flagSet.Lookup("database-host") != nil {
+		t.Error("--database-host is defined, want the db- prefix instead")
+	}
+	if config.Database.Host != "db.local" || config.Database.MaxConns != 10 || config.Database.Port != 5432 {
+		t.Errorf("Database = %+v, want the values of the db- flags", config.Database)
+	}
+	if config.Cache.Host != "cache.local" {
+		t.Errorf("Cache.Host = %q, want the default cache- prefix", config.Cache.Host)
+	}
+}
+
+func TestChanged(t *testing.T) {
+	var config struct {
+		Name string
+		Port int
+		Skip string `amalgam:"-"`
+	}
+	a := newTestAmalgam(t, &config, []string{"--port", "8080"})
+	if a.Changed("Port") {
+		t.Error("Changed(Port) = true before the flags were parsed")
+	}
+
+	loadYAML(t, a, "name: svc\n")
+	for field, want := range map[string]bool{"Port": true, "port": true, "Name": false, "Skip": false, "Missing": false} {
+		if got := a.Changed(field); got != want {
+			t.Errorf("Changed(%s) = %t, want %t", field, got, want)
+		}
+	}
+}
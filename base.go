@@ -0,0 +1,140 @@
+package amalgam
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// basePrefixes are the prefixes of integers written in each base, which are
+// accepted when parsing and added when formatting.
+var basePrefixes = map[int]string{
+	2:  "0b",
+	8:  "0o",
+	16: "0x",
+}
+
+// isIntKind reports whether the kind is a signed or unsigned integer.
+func isIntKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
+
+// intBase returns the base given by the `base` tag option of an integer
+// field, or 0 if there isn't one (or it isn't an integer field).
+func intBase(info fieldInfo) (int, error) {
+	raw, ok := info.options["base"]
+	t := info.value.Type()
+	if !ok || !isIntKind(t.Kind()) || t == durationType {
+		return 0, nil
+	}
+	base, err := strconv.Atoi(raw)
+	if err != nil || base < 2 || base > 36 {
+		return 0, fmt.Errorf("invalid base %q", raw)
+	}
+	return base, nil
+}
+
+// parseInt parses an integer of type t in the base, with an optional sign and
+// base prefix (eg. `0x1F` or `0o755`).  Octal also accepts a leading zero
+// (eg. `0755`).
+func parseInt(s string, base int, t reflect.Type) (reflect.Value, error) {
+	s = strings.TrimSpace(s)
+	digits, sign := s, ""
+	if strings.HasPrefix(digits, "-") || strings.HasPrefix(digits, "+") {
+		digits, sign = digits[1:], digits[:1]
+	}
+	if prefix := basePrefixes[base]; prefix != "" && strings.HasPrefix(strings.ToLower(digits), prefix) {
+		digits = digits[len(prefix):]
+	}
+
+	v := reflect.New(t).Elem()
+	switch t.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if sign == "-" {
+			return v, fmt.Errorf("invalid base %d integer %q", base, s)
+		}
+		n, err := strconv.ParseUint(digits, base, t.Bits())
+		if err != nil {
+			return v, fmt.Errorf("invalid base %d integer %q", base, s)
+		}
+		v.SetUint(n)
+	default:
+		n, err := strconv.ParseInt(sign+digits, base, t.Bits())
+		if err != nil {
+			return v, fmt.Errorf("invalid base %d integer %q", base, s)
+		}
+		v.SetInt(n)
+	}
+	return v, nil
+}
+
+// formatInt formats an integer in the base, with the base prefix (apart from
+// zero, so that it isn't shown as a flag default).
+func formatInt(v reflect.Value, base int) string {
+	if v.IsZero() {
+		return "0"
+	}
+	prefix := basePrefixes[base]
+	switch v.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return prefix + strconv.FormatUint(v.Uint(), base)
+	}
+	if n := v.Int(); n < 0 {
+		return "-" + prefix + strconv.FormatUint(uint64(-n), base)
+	}
+	return prefix + strconv.FormatInt(v.Int(), base)
+}
+
+// convertInt converts a raw setting for an integer field tagged with the
+// `base` option, parsing strings (eg. from the environment) in the base.
+// Numbers (eg. from the config file) are returned unchanged.
+func convertInt(info fieldInfo, raw interface{}, base int) (interface{}, error) {
+	s, ok := raw.(string)
+	if !ok {
+		return raw, nil
+	}
+	v, err := parseInt(s, base, info.value.Type())
+	if err != nil {
+		return nil, err
+	}
+	return v.Interface(), nil
+}
+
+// intValue is a pflag.Value for an integer field tagged with the `base`
+// option.
+type intValue struct {
+	value reflect.Value
+	base  int
+}
+
+func newIntValue(val reflect.Value, base int) *intValue {
+	v := reflect.New(val.Type()).Elem()
+	v.Set(val)
+	return &intValue{value: v, base: base}
+}
+
+func (i *intValue) Set(val string) error {
+	v, err := parseInt(val, i.base, i.value.Type())
+	if err != nil {
+		return err
+	}
+	i.value.Set(v)
+	return nil
+}
+
+// Type gives the base as well as the kind, eg. `int(base3)`.  viper parses
+// the values of flags whose type is the name of a signed integer kind itself,
+// in base 10, so this also keeps it from doing that.
+func (i *intValue) Type() string {
+	return fmt.Sprintf("%s(base%d)", i.value.Kind(), i.base)
+}
+
+func (i *intValue) String() string {
+	return formatInt(i.value, i.base)
+}
@@ -0,0 +1,69 @@
+package amalgam
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+type baseConfig struct {
+	Mask  uint32      `amalgam:",base=16"`
+	Mode  os.FileMode `amalgam:",base=8"`
+	Umask int         `amalgam:",base=8"`
+}
+
+func TestIntBases(t *testing.T) {
+	config := baseConfig{Mask: 0xff}
+	defer setEnv("UMASK", "022")()
+
+	a := newTestAmalgam(t, &config, []string{"--mask", "0x1F"})
+	if got := a.flagSet.Lookup("mask").DefValue; got != "0xff" {
+		t.Errorf("--mask default = %s, want 0xff", got)
+	}
+	loadYAML(t, a, "mode: \"0o755\"\n")
+
+	if config.Mask != 0x1f {
+		t.Errorf("Mask = %#x, want 0x1f", config.Mask)
+	}
+	if config.Mode != 0755 {
+		t.Errorf("Mode = %#o, want 0755", config.Mode)
+	}
+	if config.Umask != 022 {
+		t.Errorf("Umask = %#o, want 022", config.Umask)
+	}
+}
+
+func TestIntBasesInvalid(t *testing.T) {
+	for _, test := range []struct {
+		args []string
+		doc  string
+		want string
+	}{
+		{args: []string{"--mask", "0xZZ"}, want: `invalid base 16 integer "0xZZ"`},
+		{args: []string{"--mode", "0o789"}, want: `invalid base 8 integer "0o789"`},
+		{doc: "umask: \"9\"\n", want: `invalid base 8 integer "9"`},
+		{args: []string{"--mask", "-1"}, want: `invalid base 16 integer "-1"`},
+	} {
+		var config baseConfig
+		a := newTestAmalgam(t, &config, test.args)
+		assertError(t, a.Load(strings.NewReader(test.doc)), test.want)
+	}
+}
+
+func TestIntBaseFlagWithoutPrefix(t *testing.T) {
+	var config struct {
+		Trits int   `amalgam:",base=3"`
+		ID    int64 `amalgam:",base=36"`
+	}
+	a := newTestAmalgam(t, &config, []string{"--trits", "12", "--id", "zz"})
+	loadYAML(t, a, "")
+
+	// Signed integers are parsed in their base, rather than by viper in
+	// base 10.
+	if config.Trits != 5 || config.ID != 1295 {
+		t.Errorf("got %+v, want Trits 5 and ID 1295", config)
+	}
+	if got := a.flagSet.Lookup("trits").Value.Type(); got != "int(base3)" {
+		t.Errorf("--trits type = %q, want int(base3)", got)
+	}
+}
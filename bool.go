@@ -0,0 +1,41 @@
+package amalgam
+
+import (
+	"fmt"
+	"strings"
+)
+
+// strictBools are the strings accepted for bool fields by WithStrictBools.
+var strictBools = map[string]bool{
+	"true":  true,
+	"false": false,
+	"1":     true,
+	"0":     false,
+	"yes":   true,
+	"no":    false,
+	"on":    true,
+	"off":   false,
+}
+
+// WithStrictBools only accepts true/false, 1/0, yes/no and on/off (in any
+// case) as strings for bool fields, eg. from the environment, so that a typo
+// such as `FEATURE=ture` is an error naming the field and value.
+func WithStrictBools() func(*Amalgam) {
+	return func(a *Amalgam) {
+		a.strictBools = true
+	}
+}
+
+// convertBool converts a raw setting for a bool field, when the strings
+// accepted are restricted by WithStrictBools.
+func convertBool(raw interface{}) (interface{}, error) {
+	s, ok := raw.(string)
+	if !ok {
+		return raw, nil
+	}
+	b, ok := strictBools[strings.ToLower(strings.TrimSpace(s))]
+	if !ok {
+		return nil, fmt.Errorf("invalid bool %q, expected true/false, 1/0, yes/no or on/off", s)
+	}
+	return b, nil
+}
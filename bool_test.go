@@ -0,0 +1,39 @@
+package amalgam
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStrictBools(t *testing.T) {
+	for value, want := range map[string]bool{
+		"true": true, "FALSE": false, "1": true, "0": false,
+		"yes": true, "No": false, "on": true, "off": false,
+	} {
+		var config struct {
+			Feature bool
+		}
+		config.Feature = !want
+		func() {
+			defer setEnv("FEATURE", value)()
+			a := newTestAmalgam(t, &config, nil, WithStrictBools())
+			if err := a.Load(strings.NewReader("")); err != nil {
+				t.Errorf("FEATURE=%s: %v", value, err)
+			} else if config.Feature != want {
+				t.Errorf("FEATURE=%s: Feature = %v, want %v", value, config.Feature, want)
+			}
+		}()
+	}
+}
+
+func TestStrictBoolsInvalid(t *testing.T) {
+	var config struct {
+		Feature bool
+	}
+	defer setEnv("FEATURE", "maybe")()
+
+	a := newTestAmalgam(t, &config, nil, WithStrictBools())
+	err := a.Load(strings.NewReader(""))
+	assertError(t, err, "Feature")
+	assertError(t, err, `invalid bool "maybe"`)
+}
@@ -0,0 +1,138 @@
+package amalgam
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/pelletier/go-toml"
+	"gopkg.in/yaml.v2"
+)
+
+// WithCaseSensitiveKeys preserves the case of the keys in the config file,
+// which viper otherwise lowercases.  Keys of map fields keep their case (so
+// `MyKey` and `mykey` are distinct map entries), and struct fields prefer keys
+// matching the Go field name exactly, falling back to a case-insensitive
+// match.  Values from the environment and flags still take precedence.
+//
+// This is supported for JSON, YAML and TOML config files.
+func WithCaseSensitiveKeys() func(*Amalgam) {
+	return func(a *Amalgam) {
+		a.caseSensitiveKeys = true
+	}
+}
+
+// parseRawConfig parses config data without changing the case of the keys.
+// Nested maps are returned as map[string]interface{}.
+func parseRawConfig(format string, data []byte) (map[string]interface{}, error) {
+	raw := make(map[string]interface{})
+
+	switch strings.ToLower(format) {
+	case "yaml", "yml":
+		if err := yaml.Unmarshal(data, &raw); err != nil {
+			return nil, err
+		}
+	case "json":
+		// Keep numbers as json.Number, so that they don't lose precision.
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		if err := decoder.Decode(&raw); err != nil {
+			return nil, err
+		}
+	case "toml":
+		tree, err := toml.LoadBytes(data)
+		if err != nil {
+			return nil, err
+		}
+		raw = tree.ToMap()
+	default:
+		return nil, fmt.Errorf("case-sensitive keys aren't supported for %q config", format)
+	}
+
+	return stringKeyMap(raw), nil
+}
+
+// stringKeyMap converts the nested map[interface{}]interface{} values produced
+// by the YAML parser to map[string]interface{}.
+func stringKeyMap(m map[string]interface{}) map[string]interface{} {
+	for key, value := range m {
+		m[key] = stringKeys(value)
+	}
+	return m
+}
+
+func stringKeys(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			m[fmt.Sprint(key)] = stringKeys(item)
+		}
+		return m
+	case map[string]interface{}:
+		return stringKeyMap(v)
+	case []interface{}:
+		for i, item := range v {
+			v[i] = stringKeys(item)
+		}
+	}
+	return value
+}
+
+// lookupRawPath returns the value at the path of field names in a
+// case-preserved settings map, along with the path of keys it was found at.
+// Keys matching a field name exactly are preferred over case-insensitive
+// matches.
+func lookupRawPath(settings map[string]interface{}, path []string) (interface{}, []string, bool) {
+	var keys []string
+	var value interface{} = settings
+	for _, name := range path {
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, nil, false
+		}
+
+		key, found := name, false
+		if _, found = m[name]; !found {
+			for k := range m {
+				if strings.EqualFold(k, name) {
+					key, found = k, true
+					break
+				}
+			}
+		}
+		if !found {
+			return nil, nil, false
+		}
+
+		keys = append(keys, key)
+		value = m[key]
+	}
+
+	return value, keys, true
+}
+
+// caseSensitiveOverlay moves the values which came from the config file out
+// of the resolved settings, and returns them with the case of their keys
+// preserved, to be decoded after the settings.  Unchanged fields (see
+// LoadChanged) are left out.
+func (a *Amalgam) caseSensitiveOverlay(settings map[string]interface{}, unchanged map[string]bool) map[string]interface{} {
+	overlay := make(map[string]interface{})
+	for field, info := range a.fields {
+		if info.excluded() || !a.fromFile(field) {
+			continue
+		}
+		if unchanged[field] {
+			continue
+		}
+
+		value, keys, ok := lookupRawPath(a.rawFileSettings, strings.Split(field, "."))
+		if !ok {
+			continue
+		}
+		deletePath(settings, keyPath(field))
+		setPath(overlay, keys, value)
+	}
+	return overlay
+}
@@ -0,0 +1,36 @@
+package amalgam
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCaseSensitiveKeys(t *testing.T) {
+	var config struct {
+		Headers map[string]string
+		Token   string
+	}
+	a := newTestAmalgam(t, &config, nil, WithCaseSensitiveKeys())
+	loadYAML(t, a, "headers:\n  MyKey: upper\n  mykey: lower\nToken: exact\n")
+
+	if len(config.Headers) != 2 || config.Headers["MyKey"] != "upper" || config.Headers["mykey"] != "lower" {
+		t.Errorf("Headers = %v, want MyKey and mykey kept distinct", config.Headers)
+	}
+	if config.Token != "exact" {
+		t.Errorf("Token = %q, want exact", config.Token)
+	}
+}
+
+func TestCaseSensitiveKeysPreferExactFieldName(t *testing.T) {
+	var config struct {
+		Token string
+	}
+	a := newTestAmalgam(t, &config, nil, WithCaseSensitiveKeys(), WithConfigType("json"))
+	if err := a.Load(strings.NewReader(`{"token": "folded", "Token": "exact"}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	if config.Token != "exact" {
+		t.Errorf("Token = %q, want the exactly matching key", config.Token)
+	}
+}
@@ -0,0 +1,40 @@
+//go:build cobra
+// +build cobra
+
+package amalgam
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// BindCobra binds the Amalgam to the cobra command: the config flags are
+// added to the command's flags, which become the Amalgam's flag set, and the
+// config is loaded with LoadFile once cobra has parsed the flags, before the
+// command's own PreRunE or PreRun.  Only the flags added by the Amalgam are
+// added to the command, and not others in its flag set, such as those added
+// to pflag.CommandLine by other packages.
+//
+// This is only available when building with the `cobra` build tag.
+func (a *Amalgam) BindCobra(cmd *cobra.Command) {
+	for _, name := range a.ownFlags {
+		if cmd.Flags().Lookup(name) == nil {
+			cmd.Flags().AddFlag(a.flagSet.Lookup(name))
+		}
+	}
+	a.flagSet = cmd.Flags()
+
+	preRunE, preRun := cmd.PreRunE, cmd.PreRun
+	cmd.PreRun = nil
+	cmd.PreRunE = func(cmd *cobra.Command, args []string) error {
+		if err := a.LoadFile(); err != nil {
+			return err
+		}
+		if preRunE != nil {
+			return preRunE(cmd, args)
+		}
+		if preRun != nil {
+			preRun(cmd, args)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,79 @@
+//go:build cobra
+// +build cobra
+
+package amalgam_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/mrbanzai/amalgam"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+func ExampleAmalgam_BindCobra() {
+	var config struct {
+		ListenAddr string
+		Workers    int
+	}
+	config.ListenAddr = "127.0.0.1:5000"
+
+	a, err := amalgam.New(&config, amalgam.WithFlagSetName("serve"))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+
+	cmd := &cobra.Command{
+		Use: "serve",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			fmt.Println(config.ListenAddr, config.Workers)
+			return nil
+		},
+	}
+	a.BindCobra(cmd)
+
+	cmd.SetArgs([]string{"--workers", "4"})
+	if err := cmd.Execute(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+	// Output: 127.0.0.1:5000 4
+}
+
+func TestBindCobraGlobalFlagSet(t *testing.T) {
+	// Without a flag set, the Amalgam uses pflag.CommandLine, which may
+	// have other packages' flags.
+	defer func(fs *pflag.FlagSet) { pflag.CommandLine = fs }(pflag.CommandLine)
+	pflag.CommandLine = pflag.NewFlagSet("global", pflag.ContinueOnError)
+	pflag.CommandLine.Bool("verbose", false, "another package's flag")
+
+	var config struct {
+		Workers int
+	}
+	a, err := amalgam.New(&config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := &cobra.Command{Use: "serve", Run: func(*cobra.Command, []string) {}}
+	cmd.SetOut(ioutil.Discard)
+	cmd.SetErr(ioutil.Discard)
+	a.BindCobra(cmd)
+	if cmd.Flags().Lookup("workers") == nil || cmd.Flags().Lookup("config") == nil {
+		t.Error("the Amalgam's flags weren't added to the command")
+	}
+	if cmd.Flags().Lookup("verbose") != nil {
+		t.Error("got the other package's --verbose flag on the command")
+	}
+
+	cmd.SetArgs([]string{"--workers", "4"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	if config.Workers != 4 {
+		t.Errorf("Workers = %d, want 4", config.Workers)
+	}
+}
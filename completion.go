@@ -0,0 +1,61 @@
+package amalgam
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Completion describes how the value of a flag can be completed by a shell.
+type Completion struct {
+	// Values is the fixed set of accepted values, from the `oneof` tag option.
+	Values []string
+	// Files is true if the value is a file path, from the `path` tag option.
+	Files bool
+}
+
+// Complete returns the candidate values which start with toComplete.  For path
+// fields, the candidates are the files matching toComplete.
+func (c Completion) Complete(toComplete string) []string {
+	if c.Files {
+		matches, err := filepath.Glob(toComplete + "*")
+		if err != nil {
+			return nil
+		}
+		return matches
+	}
+
+	var candidates []string
+	for _, value := range c.Values {
+		if strings.HasPrefix(value, toComplete) {
+			candidates = append(candidates, value)
+		}
+	}
+	return candidates
+}
+
+// Completions returns the value completions for each flag that has any, keyed
+// by flag name.  Fields tagged with `oneof=a|b|c` complete to the listed values,
+// and fields tagged with `path` complete to file names.
+func (a *Amalgam) Completions() map[string]Completion {
+	completions := make(map[string]Completion)
+
+	for _, info := range a.fields {
+		if info.excluded() {
+			continue
+		}
+
+		var c Completion
+		if values, ok := info.options["oneof"]; ok && values != "" {
+			c.Values = strings.Split(values, "|")
+			sort.Strings(c.Values)
+		}
+		c.Files = info.options.has("path")
+
+		if c.Files || len(c.Values) > 0 {
+			completions[info.flagName] = c
+		}
+	}
+
+	return completions
+}
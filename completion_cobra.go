@@ -0,0 +1,40 @@
+//go:build cobra
+// +build cobra
+
+package amalgam
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// RegisterCompletions registers the flag value completions returned by
+// Completions with the cobra command.  The flags must be part of the command's
+// flag set, ie. the Amalgam should have been created with
+// WithFlagSet(cmd.Flags()).
+//
+// This is only available when building with the `cobra` build tag.
+func (a *Amalgam) RegisterCompletions(cmd *cobra.Command) error {
+	return a.named(a.registerCompletions(cmd))
+}
+
+// registerCompletions does the work of RegisterCompletions.
+func (a *Amalgam) registerCompletions(cmd *cobra.Command) error {
+	for name, c := range a.Completions() {
+		if c.Files {
+			if err := cmd.MarkFlagFilename(name); err != nil {
+				return err
+			}
+			continue
+		}
+
+		c := c
+		err := cmd.RegisterFlagCompletionFunc(name, func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return c.Complete(toComplete), cobra.ShellCompDirectiveNoFileComp
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,41 @@
+//go:build cobra
+// +build cobra
+
+package amalgam
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestRegisterCompletions(t *testing.T) {
+	var config struct {
+		Format   string `amalgam:",oneof=yaml|json|text"`
+		CertFile string `amalgam:",path"`
+	}
+	cmd := &cobra.Command{Use: "app", Run: func(*cobra.Command, []string) {}}
+	a := newTestAmalgam(t, &config, nil, WithFlagSet(cmd.Flags()))
+	if err := a.RegisterCompletions(cmd); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := cmd.Flags().Lookup("cert-file").Annotations[cobra.BashCompFilenameExt]; !ok {
+		t.Error("--cert-file isn't marked as a file name")
+	}
+
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs([]string{cobra.ShellCompRequestCmd, "--format", "y"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	got := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if want := []string{"yaml", ":4"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("completing --format y printed %q, want %q", got, want)
+	}
+}
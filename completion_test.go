@@ -0,0 +1,47 @@
+package amalgam
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestCompletions(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+	writeFile(t, dir, "app.yaml", "")
+	writeFile(t, dir, "app.json", "")
+	writeFile(t, dir, "other.yaml", "")
+
+	var config struct {
+		Format   string `amalgam:",oneof=yaml|json|text"`
+		CertFile string `amalgam:",path"`
+		Name     string
+	}
+	a := newTestAmalgam(t, &config, nil)
+
+	completions := a.Completions()
+	if len(completions) != 2 {
+		t.Errorf("got completions for %d flags, want 2: %+v", len(completions), completions)
+	}
+
+	format := completions["format"]
+	if want := []string{"json", "text", "yaml"}; !reflect.DeepEqual(format.Values, want) {
+		t.Errorf("--format values = %q, want %q", format.Values, want)
+	}
+	if got, want := format.Complete("t"), []string{"text"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("--format completes t to %q, want %q", got, want)
+	}
+	if got := format.Complete("x"); len(got) != 0 {
+		t.Errorf("--format completes x to %q, want nothing", got)
+	}
+
+	path := completions["cert-file"]
+	if !path.Files || len(path.Values) != 0 {
+		t.Fatalf("--cert-file completion = %+v, want files", path)
+	}
+	want := []string{filepath.Join(dir, "app.json"), filepath.Join(dir, "app.yaml")}
+	if got := path.Complete(filepath.Join(dir, "app")); !reflect.DeepEqual(got, want) {
+		t.Errorf("--cert-file completes app to %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,117 @@
+package amalgam
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/mitchellh/mapstructure"
+)
+
+// defaultFunc computes the default value of a field when the config is
+// defined.
+type defaultFunc struct {
+	field string
+	fn    func() interface{}
+}
+
+// WithDefaultFunc computes the default value of a field (the dotted field
+// path, eg. `Workers`) with fn in New, eg. from the number of CPUs or the
+// hostname, in place of the value in the config object.  The value is used as
+// the flag default and the config default, but the config object is only
+// updated when loading.  It must be of the field's kind, or a number which the
+// field can hold exactly.
+func WithDefaultFunc(field string, fn func() interface{}) func(*Amalgam) {
+	return func(a *Amalgam) {
+		a.defaultFuncs = append(a.defaultFuncs, defaultFunc{field: field, fn: fn})
+	}
+}
+
+// applyDefaultFuncs computes the defaults of the fields with default
+// functions, before the flags are defined.
+func (a *Amalgam) applyDefaultFuncs() error {
+	for _, d := range a.defaultFuncs {
+		field, info, ok := a.lookupField(d.field)
+		if !ok {
+			return fmt.Errorf("default for unknown field %q", d.field)
+		}
+
+		t := info.value.Type()
+		value := d.fn()
+		v := reflect.ValueOf(value)
+		switch {
+		case v.IsValid() && v.Kind() == t.Kind() && v.Type().ConvertibleTo(t):
+			v = v.Convert(t)
+		case v.IsValid() && isNumericKind(v.Kind()) && isNumericKind(t.Kind()):
+			if v, ok = convertNumber(v, t); !ok {
+				return fmt.Errorf("%s: default %#v is out of range for the field type %s", field, value, t)
+			}
+		default:
+			return fmt.Errorf("%s: default %#v doesn't match the field type %s", field, value, t)
+		}
+		a.setFieldDefault(field, v)
+	}
+	return nil
+}
+
+// setFieldDefault sets the computed default of a field, which is used as its
+// flag and viper default in place of the field's value.
+func (a *Amalgam) setFieldDefault(field string, v reflect.Value) {
+	if a.fieldDefaults == nil {
+		a.fieldDefaults = make(map[string]reflect.Value)
+	}
+	a.fieldDefaults[field] = v
+}
+
+// defaultInfo returns the field info with the field's computed default, if
+// any, as its value.
+func (a *Amalgam) defaultInfo(field string, info fieldInfo) fieldInfo {
+	if v, ok := a.fieldDefaults[field]; ok {
+		info.value = v
+	}
+	return info
+}
+
+// applyTagDefaults computes the defaults of the fields with the `default` tag
+// option, before the default functions are applied and the flags are defined,
+// so that the flag default and the viper default always agree.  The value is
+// parsed as an environment variable would be, except that the items of a
+// slice or map are separated by `|`.
+func (a *Amalgam) applyTagDefaults() error {
+	var errs ErrorList
+	for _, field := range a.sortedFields() {
+		info := a.fields[field]
+		raw, ok := info.options["default"]
+		if info.excluded() || !ok {
+			continue
+		}
+
+		switch info.value.Kind() {
+		case reflect.Slice:
+			raw = strings.Replace(raw, "|", ",", -1)
+		case reflect.Map:
+			entrySep, _ := a.mapSeparators()
+			raw = strings.Replace(raw, "|", entrySep, -1)
+		}
+
+		value, err := a.convertField(info, raw)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: invalid default %q: %v", field, raw, err))
+			continue
+		}
+
+		t := info.value.Type()
+		target := reflect.New(t)
+		decoder, err := mapstructure.NewDecoder(a.decoderConfig(target.Interface()))
+		if err != nil {
+			return err
+		}
+		if err := decoder.Decode(value); err != nil {
+			errs = append(errs, fmt.Errorf("%s: invalid default %q for %s", field, raw, t))
+			continue
+		}
+		a.setFieldDefault(field, target.Elem())
+	}
+
+	return errs.err()
+}
@@ -0,0 +1,133 @@
+package amalgam
+
+import (
+	"reflect"
+	"runtime"
+	"strconv"
+	"testing"
+
+	"github.com/spf13/pflag"
+)
+
+func TestDefaultFunc(t *testing.T) {
+	var config struct {
+		Workers int
+		MaxJobs int64
+		Host    string
+	}
+	a := newTestAmalgam(t, &config, nil,
+		WithDefaultFunc("workers", func() interface{} { return runtime.NumCPU() }),
+		WithDefaultFunc("maxjobs", func() interface{} { return 2 * runtime.NumCPU() }),
+		WithDefaultFunc("Host", func() interface{} { return "computed" }),
+	)
+	if config.Workers != 0 {
+		t.Errorf("Workers = %d, want the config object unchanged until loading", config.Workers)
+	}
+	if got := a.flagSet.Lookup("workers").DefValue; got != strconv.Itoa(runtime.NumCPU()) {
+		t.Errorf("--workers default = %s, want runtime.NumCPU()", got)
+	}
+	loadYAML(t, a, "host: from-file\n")
+
+	if config.Workers != runtime.NumCPU() {
+		t.Errorf("Workers = %d, want %d", config.Workers, runtime.NumCPU())
+	}
+	if config.MaxJobs != int64(2*runtime.NumCPU()) {
+		t.Errorf("MaxJobs = %d, want the int default converted", config.MaxJobs)
+	}
+	if config.Host != "from-file" {
+		t.Errorf("Host = %q, want the file over the default", config.Host)
+	}
+}
+
+func TestDefaultFuncErrors(t *testing.T) {
+	var config struct {
+		Workers uint8
+		Name    string
+	}
+	for _, test := range []struct {
+		option Option
+		want   string
+	}{
+		{WithDefaultFunc("name", func() interface{} { return 3 }), "Name: default 3 doesn't match the field type string"},
+		{WithDefaultFunc("workers", func() interface{} { return 300 }), "Workers: default 300 is out of range for the field type uint8"},
+		{WithDefaultFunc("workers", func() interface{} { return -1 }), "is out of range"},
+		{WithDefaultFunc("missing", func() interface{} { return 1 }), `default for unknown field "missing"`},
+	} {
+		_, err := New(&config, WithFlagSet(pflag.NewFlagSet("test", pflag.ContinueOnError)), test.option)
+		assertError(t, err, test.want)
+	}
+}
+
+func TestTagDefaultPrecedence(t *testing.T) {
+	type untagged struct {
+		Port int
+	}
+	type tagged struct {
+		Port int `amalgam:",default=9090"`
+	}
+	for _, test := range []struct {
+		name    string
+		literal int
+		tagged  bool
+		want    int
+	}{
+		{"no defaults", 0, false, 0},
+		{"struct literal", 8080, false, 8080},
+		{"tag default", 0, true, 9090},
+		{"tag over struct literal", 8080, true, 9090},
+	} {
+		var configObj interface{}
+		var port *int
+		if test.tagged {
+			config := &tagged{Port: test.literal}
+			configObj, port = config, &config.Port
+		} else {
+			config := &untagged{Port: test.literal}
+			configObj, port = config, &config.Port
+		}
+
+		a := newTestAmalgam(t, configObj, nil)
+		if got := a.flagSet.Lookup("port").DefValue; got != strconv.Itoa(test.want) {
+			t.Errorf("%s: --port default = %s, want %d", test.name, got, test.want)
+		}
+		if got := a.viper.Get("port"); got != test.want {
+			t.Errorf("%s: config default = %v, want %d", test.name, got, test.want)
+		}
+		loadYAML(t, a, "")
+		if *port != test.want {
+			t.Errorf("%s: Port = %d, want %d", test.name, *port, test.want)
+		}
+	}
+}
+
+func TestTagDefaultOverridden(t *testing.T) {
+	type config struct {
+		Port  int      `amalgam:",default=9090"`
+		Users []string `amalgam:",default=alice|bob"`
+	}
+	for _, test := range []struct {
+		name string
+		args []string
+		env  string
+		doc  string
+		want int
+	}{
+		{"default", nil, "", "", 9090},
+		{"file", nil, "", "port: 1000\n", 1000},
+		{"env", nil, "2000", "port: 1000\n", 2000},
+		{"flag", []string{"--port", "3000"}, "2000", "port: 1000\n", 3000},
+	} {
+		if test.env != "" {
+			defer setEnv("PORT", test.env)()
+		}
+		var c config
+		a := newTestAmalgam(t, &c, test.args)
+		loadYAML(t, a, test.doc)
+		if c.Port != test.want {
+			t.Errorf("%s: Port = %d, want %d", test.name, c.Port, test.want)
+		}
+		if want := []string{"alice", "bob"}; !reflect.DeepEqual(c.Users, want) {
+			t.Errorf("%s: Users = %q, want %q", test.name, c.Users, want)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package amalgam
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// WithStrictDefinition checks the config struct definition in New, and
+// returns an ErrorList of every problem found, such as fields of unsupported
+// types, malformed tag options and colliding flag names.  Without it, some of
+// these are only reported when loading, or not at all.
+func WithStrictDefinition() func(*Amalgam) {
+	return func(a *Amalgam) {
+		a.strictDefinition = true
+	}
+}
+
+// valueOptions lists the tag options which require a value.
+var valueOptions = map[string]bool{
+	"addrtype":          true,
+	"bareunit":          true,
+	"base":              true,
+	"default":           true,
+	"defaultenv":        true,
+	"durationunit":      true,
+	"env":               true,
+	"group":             true,
+	"inline":            true,
+	"layout":            true,
+	"mutexgroup":        true,
+	"oneof":             true,
+	"requireexactlyone": true,
+	"requiredwith":      true,
+	"requiredwithout":   true,
+	"short":             true,
+	"valuefile":         true,
+}
+
+// flagOptions lists the tag options which don't take a value.
+var flagOptions = map[string]bool{
+	"filewins":     true,
+	"keyvalue":     true,
+	"path":         true,
+	"remain":       true,
+	"requiredflag": true,
+	"secret":       true,
+}
+
+// checkDefinition checks the fields in the field map, before their flags are
+// defined, and returns an ErrorList of the problems found.
+func (a *Amalgam) checkDefinition() error {
+	var errs ErrorList
+	flagFields := make(map[string]string)
+	for _, field := range a.sortedFields() {
+		info := a.fields[field]
+		if info.excluded() {
+			continue
+		}
+
+		for _, err := range a.checkField(field, info) {
+			errs = append(errs, fmt.Errorf("%s: %v", field, err))
+		}
+
+		name := info.flagName
+		if name == "" {
+			name = a.defaultFlagName(field, info)
+		}
+		if other, ok := flagFields[name]; ok {
+			errs = append(errs, fmt.Errorf("%s: flag --%s is also used by %s", field, name, other))
+		} else if a.flagSet.Lookup(name) != nil {
+			errs = append(errs, fmt.Errorf("%s: flag --%s is already defined", field, name))
+		}
+		flagFields[name] = field
+	}
+
+	return errs.err()
+}
+
+// checkField returns the problems with a field's type and tag options.
+func (a *Amalgam) checkField(field string, info fieldInfo) []error {
+	var errs []error
+	t := info.value.Type()
+
+	for _, name := range sortedOptions(info.options) {
+		value := info.options[name]
+		if valueOptions[name] && value == "" {
+			errs = append(errs, fmt.Errorf("tag option %s needs a value", name))
+		} else if flagOptions[name] && value != "" {
+			errs = append(errs, fmt.Errorf("tag option %s doesn't take a value", name))
+		}
+	}
+
+	switch {
+	case info.options.has("inline"):
+		if _, err := inlineFormat(info); err != nil {
+			errs = append(errs, err)
+		}
+	case t == addrType:
+		if _, err := addrNetwork(info); err != nil {
+			errs = append(errs, err)
+		}
+	case isValueType(t):
+	default:
+		switch t.Kind() {
+		case reflect.Chan, reflect.Func, reflect.Complex64, reflect.Complex128,
+			reflect.UnsafePointer, reflect.Uintptr:
+			errs = append(errs, fmt.Errorf("unsupported type %s", t))
+		}
+	}
+
+	if unit := info.options["durationunit"]; unit != "" {
+		if t != durationType {
+			errs = append(errs, errors.New("tag option durationunit is only supported for time.Duration fields"))
+		} else if _, err := formatDurationIn(0, unit); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if info.options["bareunit"] != "" {
+		if t != durationType {
+			errs = append(errs, errors.New("tag option bareunit is only supported for time.Duration fields"))
+		} else if _, err := a.durationUnit(info); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if info.options["base"] != "" {
+		if !isIntKind(t.Kind()) || t == durationType {
+			errs = append(errs, errors.New("tag option base is only supported for integer fields"))
+		} else if _, err := intBase(info); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	timeField := t == timeType || ((t.Kind() == reflect.Slice || t.Kind() == reflect.Map) && t.Elem() == timeType)
+	if info.options["layout"] != "" && !timeField {
+		errs = append(errs, errors.New("tag option layout is only supported for time fields"))
+	}
+	if info.options.has("keyvalue") && t != reflect.TypeOf([]string(nil)) {
+		errs = append(errs, errors.New("tag option keyvalue is only supported for []string fields"))
+	}
+	if info.options.has("remain") && t != remainType {
+		errs = append(errs, errors.New("tag option remain is only supported for map[string]interface{} fields"))
+	}
+	if info.options["addrtype"] != "" && t != addrType {
+		errs = append(errs, errors.New("tag option addrtype is only supported for net.Addr fields"))
+	}
+
+	for _, option := range []string{"requiredwith", "requiredwithout"} {
+		for _, sibling := range strings.Split(info.options[option], "|") {
+			if sibling == "" {
+				continue
+			}
+			if _, err := a.siblingSet(field, sibling); err != nil {
+				errs = append(errs, fmt.Errorf("unknown field %q in tag option %s", sibling, option))
+			}
+		}
+	}
+
+	return errs
+}
+
+// checkShorthands checks the flag shorthands given by the `short` tag option,
+// before any flags are defined, so that a shorthand used twice is reported
+// against both fields rather than making pflag panic.
+func (a *Amalgam) checkShorthands() error {
+	var errs ErrorList
+	shortFields := make(map[string]string)
+	for _, field := range a.sortedFields() {
+		info := a.fields[field]
+		short, ok := info.options["short"]
+		if info.excluded() || !ok {
+			continue
+		}
+
+		switch {
+		case len(short) != 1:
+			errs = append(errs, fmt.Errorf("%s: flag shorthand %q must be a single ASCII character", field, short))
+		case shortFields[short] != "":
+			errs = append(errs, fmt.Errorf("%s: flag shorthand -%s is also used by %s", field, short, shortFields[short]))
+		case a.flagSet.ShorthandLookup(short) != nil:
+			errs = append(errs, fmt.Errorf("%s: flag shorthand -%s is already used by --%s", field, short, a.flagSet.ShorthandLookup(short).Name))
+		default:
+			shortFields[short] = field
+		}
+	}
+
+	return errs.err()
+}
+
+// sortedOptions returns the names of the tag options in order.
+func sortedOptions(options tagOptions) []string {
+	names := make([]string, 0, len(options))
+	for name := range options {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
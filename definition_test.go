@@ -0,0 +1,90 @@
+package amalgam
+
+import (
+	"io/ioutil"
+	"reflect"
+	"testing"
+
+	"github.com/spf13/pflag"
+)
+
+// newStrict creates an Amalgam with WithStrictDefinition, returning the error.
+func newStrict(config interface{}, options ...Option) error {
+	fs := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	options = append([]Option{WithFlagSet(fs), WithArgs([]string{}), WithStrictDefinition()}, options...)
+	_, err := New(config, options...)
+	return err
+}
+
+func TestStrictDefinition(t *testing.T) {
+	var config struct {
+		Timeout int    `amalgam:",layout"`
+		Port    int    `amalgam:"port"`
+		Other   int    `amalgam:"port"`
+		Name    string `amalgam:",secret=yes"`
+		Handler func()
+	}
+	err := newStrict(&config)
+	for _, want := range []string{
+		"Port: flag --port is also used by Other",
+		"Name: tag option secret doesn't take a value",
+		"Timeout: tag option layout needs a value",
+		"Handler: unsupported type func()",
+	} {
+		assertError(t, err, want)
+	}
+}
+
+func TestStrictDefinitionValid(t *testing.T) {
+	var config struct {
+		Name  string `amalgam:"name,short=n"`
+		Port  int
+		Extra interface{}
+	}
+	if err := newStrict(&config); err != nil {
+		t.Fatal(err)
+	}
+
+	// Interface fields get no flag, but are decoded from the config file.
+	a := newTestAmalgam(t, &config, nil, WithStrictDefinition())
+	loadYAML(t, a, "extra: [1, 2]\n")
+	if want := []interface{}{1, 2}; !reflect.DeepEqual(config.Extra, want) {
+		t.Errorf("Extra = %#v, want %#v", config.Extra, want)
+	}
+}
+
+func TestShorthandCollision(t *testing.T) {
+	var config struct {
+		Port    int    `amalgam:",short=p"`
+		Profile string `amalgam:",short=p"`
+	}
+	// Shorthand collisions are always an error, rather than a pflag panic.
+	_, err := New(&config, WithFlagSet(pflag.NewFlagSet("test", pflag.ContinueOnError)), WithArgs([]string{}))
+	assertError(t, err, "Profile: flag shorthand -p is also used by Port")
+}
+
+func TestShorthandCollisionWithExistingFlag(t *testing.T) {
+	var config struct {
+		Cert string `amalgam:",short=c"`
+	}
+	_, err := New(&config, WithFlagSet(pflag.NewFlagSet("test", pflag.ContinueOnError)), WithArgs([]string{}))
+	assertError(t, err, "Cert: flag shorthand -c is already used by --config")
+}
+
+func TestShorthand(t *testing.T) {
+	var config struct {
+		Port    int `amalgam:",short=p"`
+		Backend struct {
+			Host string
+		} `amalgam:",inline=json,short=b"`
+	}
+	a := newTestAmalgam(t, &config, []string{"-p", "8080", "-b", `{"host": "db.local"}`})
+	loadYAML(t, a, "")
+	if config.Port != 8080 {
+		t.Errorf("Port = %d, want 8080", config.Port)
+	}
+	if config.Backend.Host != "db.local" {
+		t.Errorf("Backend.Host = %q, want the inline field's shorthand parsed", config.Backend.Host)
+	}
+}
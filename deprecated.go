@@ -0,0 +1,48 @@
+package amalgam
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Warnings returns the warnings from the last load, such as the use of
+// deprecated flags, config keys or environment variables (fields tagged with
+// the `deprecated` option).
+func (a *Amalgam) Warnings() []string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	return append([]string(nil), a.warnings...)
+}
+
+// deprecationWarnings returns a warning for each source which provided a
+// value for a deprecated field.
+func (a *Amalgam) deprecationWarnings() []string {
+	var warnings []string
+	for _, field := range a.sortedFields() {
+		info := a.fields[field]
+		if info.excluded() || !info.options.has("deprecated") {
+			continue
+		}
+
+		suffix := ""
+		if msg := info.options["deprecated"]; msg != "" {
+			suffix = ": " + msg
+		}
+
+		if flag := a.flagSet.Lookup(info.flagName); flag != nil && flag.Changed {
+			warnings = append(warnings, fmt.Sprintf("flag --%s is deprecated%s", info.flagName, suffix))
+		}
+		for _, name := range a.envVarNames(field) {
+			if _, ok := os.LookupEnv(name); ok {
+				warnings = append(warnings, fmt.Sprintf("environment variable %s is deprecated%s", name, suffix))
+				break
+			}
+		}
+		if a.inFile(field) {
+			warnings = append(warnings, fmt.Sprintf("config key %s is deprecated%s", strings.ToLower(field), suffix))
+		}
+	}
+	return warnings
+}
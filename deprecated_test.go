@@ -0,0 +1,39 @@
+package amalgam
+
+import (
+	"reflect"
+	"testing"
+)
+
+type deprecatedConfig struct {
+	Port    int
+	OldPort int    `amalgam:",deprecated=use port"`
+	Legacy  string `amalgam:",deprecated"`
+}
+
+func TestDeprecationWarnings(t *testing.T) {
+	var config deprecatedConfig
+	defer setEnv("LEGACY", "x")()
+
+	a := newTestAmalgam(t, &config, []string{"--old-port", "80"})
+	loadYAML(t, a, "oldport: 81\n")
+
+	want := []string{
+		"environment variable LEGACY is deprecated",
+		"flag --old-port is deprecated: use port",
+		"config key oldport is deprecated: use port",
+	}
+	if got := a.Warnings(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Warnings() = %q, want %q", got, want)
+	}
+}
+
+func TestNoDeprecationWarnings(t *testing.T) {
+	var config deprecatedConfig
+	a := newTestAmalgam(t, &config, []string{"--port", "80"})
+	loadYAML(t, a, "port: 81\n")
+
+	if got := a.Warnings(); len(got) != 0 {
+		t.Errorf("Warnings() = %q, want none", got)
+	}
+}
@@ -0,0 +1,61 @@
+package amalgam
+
+import "reflect"
+
+// Settings returns the resolved value of each config field, keyed by the
+// dotted field path (eg. `API.Endpoint`).  Excluded fields are omitted.
+// Slices and maps are copied, so the result isn't affected by later loads.
+func (a *Amalgam) Settings() map[string]interface{} {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	settings := make(map[string]interface{}, len(a.fields))
+	for field, info := range a.fields {
+		if info.excluded() {
+			continue
+		}
+
+		v := a.fieldValue(info)
+		if !v.IsValid() {
+			settings[field] = nil
+			continue
+		}
+		settings[field] = copyValue(v)
+	}
+	return settings
+}
+
+// Diff compares the resolved settings of the Amalgam with those of other
+// (eg. before and after a reload), and returns the fields whose values
+// differ, keyed by the dotted field path, with the Amalgam's value first and
+// other's second.
+func (a *Amalgam) Diff(other *Amalgam) map[string][2]interface{} {
+	mine, theirs := a.Settings(), other.Settings()
+
+	diff := make(map[string][2]interface{})
+	for field, value := range mine {
+		if otherValue, ok := theirs[field]; !ok || !reflect.DeepEqual(value, otherValue) {
+			diff[field] = [2]interface{}{value, otherValue}
+		}
+	}
+	for field, otherValue := range theirs {
+		if _, ok := mine[field]; !ok {
+			diff[field] = [2]interface{}{nil, otherValue}
+		}
+	}
+	return diff
+}
+
+// copyValue returns the value, copying slices and maps so that the copy
+// doesn't share them with the config object.
+func copyValue(v reflect.Value) interface{} {
+	if v.Kind() != reflect.Map || v.IsNil() {
+		return copySlice(v)
+	}
+
+	c := reflect.MakeMapWithSize(v.Type(), v.Len())
+	for _, key := range v.MapKeys() {
+		c.SetMapIndex(key, v.MapIndex(key))
+	}
+	return c.Interface()
+}
@@ -0,0 +1,52 @@
+package amalgam
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	var before struct {
+		Name     string
+		Legacy   string
+		Labels   map[string]string
+		Database struct {
+			Host string
+			Port int
+		}
+	}
+	a := newTestAmalgam(t, &before, nil)
+	loadYAML(t, a, "name: svc\nlegacy: old\nlabels:\n  team: core\ndatabase:\n  host: db\n  port: 5432\n")
+
+	var after struct {
+		Name     string
+		Labels   map[string]string
+		Database struct {
+			Host string
+			Port int
+			User string
+		}
+	}
+	b := newTestAmalgam(t, &after, nil)
+	loadYAML(t, b, "name: svc\nlabels:\n  team: core\ndatabase:\n  host: db\n  port: 6432\n  user: app\n")
+
+	want := map[string][2]interface{}{
+		"Legacy":        {"old", nil},
+		"Database.Port": {5432, 6432},
+		"Database.User": {nil, "app"},
+	}
+	if got := a.Diff(b); !reflect.DeepEqual(got, want) {
+		t.Errorf("got diff %v, want %v", got, want)
+	}
+
+	// The settings are copies, so later changes to maps don't show up in
+	// them.
+	settings := a.Settings()
+	before.Labels["team"] = "edge"
+	if got := settings["Labels"]; !reflect.DeepEqual(got, map[string]string{"team": "core"}) {
+		t.Errorf("Labels setting = %v, want it unaffected by changes to the config", got)
+	}
+	if got := a.Diff(b)["Labels"]; !reflect.DeepEqual(got, [2]interface{}{map[string]string{"team": "edge"}, map[string]string{"team": "core"}}) {
+		t.Errorf("Labels diff = %v, want the changed map", got)
+	}
+}
@@ -0,0 +1,87 @@
+package amalgam
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DocsMarkdown returns markdown documentation of the flags for the config
+// fields, as a table of the flag name, environment variable, type, default
+// and description.  Fields are listed in declaration order, with the fields
+// of each nested struct under a heading of the struct's path (eg.
+// `## API`).
+func (a *Amalgam) DocsMarkdown() string {
+	var groups []string
+	rows := make(map[string][]string)
+	for _, field := range a.declaredFields() {
+		info := a.fields[field]
+		if info.excluded() {
+			continue
+		}
+		flag := a.flagSet.Lookup(info.flagName)
+		if flag == nil {
+			continue
+		}
+
+		group := ""
+		if idx := strings.LastIndex(field, "."); idx >= 0 {
+			group = field[:idx]
+		}
+		if _, ok := rows[group]; !ok {
+			groups = append(groups, group)
+		}
+
+		// pflag gives nil defaults (eg. of a net.IPMask) as <nil>, which
+		// its usage leaves out too.
+		def := ""
+		if flag.DefValue != "" && flag.DefValue != "<nil>" {
+			def = "`" + flag.DefValue + "`"
+		}
+		env := a.primaryEnvVarName(field)
+		rows[group] = append(rows[group], fmt.Sprintf("| `--%s` | `%s` | %s | %s | %s |",
+			flag.Name, env, flag.Value.Type(), def, escapeMarkdown(flag.Usage)))
+	}
+
+	var b strings.Builder
+	for _, group := range groups {
+		if b.Len() > 0 {
+			b.WriteString("\n")
+		}
+		if group != "" {
+			b.WriteString("## " + group + "\n\n")
+		}
+		b.WriteString("| Flag | Environment | Type | Default | Description |\n")
+		b.WriteString("| --- | --- | --- | --- | --- |\n")
+		for _, row := range rows[group] {
+			b.WriteString(row + "\n")
+		}
+	}
+
+	return b.String()
+}
+
+// declaredFields returns the fields in the order they're declared in the
+// config struct.
+func (a *Amalgam) declaredFields() []string {
+	fields := make([]string, 0, len(a.fields))
+	for field := range a.fields {
+		fields = append(fields, field)
+	}
+	sort.Slice(fields, func(i, j int) bool {
+		x, y := a.fields[fields[i]].index, a.fields[fields[j]].index
+		for k := 0; k < len(x) && k < len(y); k++ {
+			if x[k] != y[k] {
+				return x[k] < y[k]
+			}
+		}
+		return len(x) < len(y)
+	})
+	return fields
+}
+
+// escapeMarkdown escapes the characters which would break a markdown table
+// cell.
+func escapeMarkdown(s string) string {
+	return strings.NewReplacer("|", `\|`, "\n", " ").Replace(s)
+}
@@ -0,0 +1,50 @@
+package amalgam
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDocsMarkdown(t *testing.T) {
+	var config struct {
+		Name     string        `amalgam:",Service name"`
+		Timeout  time.Duration `amalgam:",Request timeout"`
+		Mask     net.IPMask    `amalgam:",Allowed mask"`
+		Database struct {
+			Port int    `amalgam:",Port | port number"`
+			User string `amalgam:",Database user"`
+		}
+		Workers int `amalgam:",Worker count"`
+		Skip    int `amalgam:"-"`
+	}
+	config.Name = "svc"
+	config.Timeout = 5 * time.Second
+	config.Database.Port = 5432
+	a := newTestAmalgam(t, &config, nil)
+
+	// Fields are in declaration order, with nested structs under their own
+	// heading, and nil defaults left out.
+	want := strings.Join([]string{
+		"| Flag | Environment | Type | Default | Description |",
+		"| --- | --- | --- | --- | --- |",
+		"| `--name` | `NAME` | string | `svc` | Service name |",
+		"| `--timeout` | `TIMEOUT` | duration | `5s` | Request timeout |",
+		"| `--mask` | `MASK` | ipMask |  | Allowed mask |",
+		"| `--workers` | `WORKERS` | int | `0` | Worker count |",
+		"",
+		"## Database",
+		"",
+		"| Flag | Environment | Type | Default | Description |",
+		"| --- | --- | --- | --- | --- |",
+		"| `--database-port` | `DATABASE_PORT` | int | `5432` | Port \\| port number |",
+		"| `--database-user` | `DATABASE_USER` | string |  | Database user |",
+		"",
+	}, "\n")
+	for i := 0; i < 5; i++ {
+		if got := a.DocsMarkdown(); got != want {
+			t.Fatalf("got docs:\n%s\nwant:\n%s", got, want)
+		}
+	}
+}
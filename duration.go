@@ -0,0 +1,94 @@
+package amalgam
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WithBareDurationUnit interprets bare numbers given for time.Duration fields
+// (eg. `--timeout 30`) in the unit, rather than rejecting them.  Values with a
+// unit (eg. `30s`) are parsed as normal.  Individual fields can opt in with
+// the `bareunit` tag option instead (eg. `bareunit=s`).
+func WithBareDurationUnit(unit time.Duration) func(*Amalgam) {
+	return func(a *Amalgam) {
+		a.bareDurationUnit = unit
+	}
+}
+
+// durationUnit returns the unit for bare numbers given for the field, or 0 if
+// bare numbers aren't accepted.
+func (a *Amalgam) durationUnit(info fieldInfo) (time.Duration, error) {
+	if unit, ok := info.options["bareunit"]; ok {
+		d, err := time.ParseDuration("1" + unit)
+		if err != nil {
+			return 0, fmt.Errorf("invalid bareunit %q", unit)
+		}
+		return d, nil
+	}
+	return a.bareDurationUnit, nil
+}
+
+// formatDurationIn formats a duration as a number of the unit (eg. `90s`
+// rather than `1m30s` for a unit of `s`), for the `durationunit` tag option.
+func formatDurationIn(d time.Duration, unit string) (string, error) {
+	size, err := time.ParseDuration("1" + unit)
+	if err != nil || size <= 0 {
+		return "", fmt.Errorf("invalid durationunit %q", unit)
+	}
+	return strconv.FormatFloat(float64(d)/float64(size), 'f', -1, 64) + unit, nil
+}
+
+// parseDuration parses a duration, interpreting a bare number in the unit.
+func parseDuration(s string, unit time.Duration) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if n, err := strconv.ParseFloat(s, 64); err == nil && unit != 0 {
+		return time.Duration(n * float64(unit)), nil
+	}
+	return time.ParseDuration(s)
+}
+
+// convertBareDuration converts a raw setting for a time.Duration field,
+// interpreting bare numbers in the unit.
+func convertBareDuration(raw interface{}, unit time.Duration) (interface{}, error) {
+	switch v := raw.(type) {
+	case string:
+		return parseDuration(v, unit)
+	case int:
+		return time.Duration(v) * unit, nil
+	case int64:
+		return time.Duration(v) * unit, nil
+	case float64:
+		return time.Duration(v * float64(unit)), nil
+	}
+	return raw, nil
+}
+
+// durationValue is a pflag.Value for a time.Duration flag which accepts bare
+// numbers in a unit.
+type durationValue struct {
+	value time.Duration
+	unit  time.Duration
+}
+
+func newDurationValue(val time.Duration, unit time.Duration) *durationValue {
+	return &durationValue{value: val, unit: unit}
+}
+
+func (d *durationValue) Set(val string) error {
+	v, err := parseDuration(val, d.unit)
+	if err != nil {
+		return err
+	}
+	d.value = v
+	return nil
+}
+
+func (d *durationValue) Type() string {
+	return "duration"
+}
+
+func (d *durationValue) String() string {
+	return d.value.String()
+}
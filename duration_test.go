@@ -0,0 +1,71 @@
+package amalgam
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBareDurationUnit(t *testing.T) {
+	for _, test := range []struct {
+		arg  string
+		want time.Duration
+	}{
+		{"30", 30 * time.Second},
+		{"30s", 30 * time.Second},
+		{"2m", 2 * time.Minute},
+	} {
+		var config struct {
+			Timeout time.Duration
+		}
+		a := newTestAmalgam(t, &config, []string{"--timeout", test.arg}, WithBareDurationUnit(time.Second))
+		loadYAML(t, a, "")
+		if config.Timeout != test.want {
+			t.Errorf("--timeout %s: got %v, want %v", test.arg, config.Timeout, test.want)
+		}
+	}
+}
+
+func TestBareDurationUnitTag(t *testing.T) {
+	var config struct {
+		Timeout time.Duration `amalgam:",bareunit=ms"`
+		Wait    time.Duration
+	}
+	a := newTestAmalgam(t, &config, []string{"--timeout", "250"})
+	loadYAML(t, a, "")
+	if config.Timeout != 250*time.Millisecond {
+		t.Errorf("Timeout = %v, want 250ms", config.Timeout)
+	}
+
+	a = newTestAmalgam(t, &config, []string{"--wait", "30"})
+	assertError(t, a.Load(strings.NewReader("")), "--wait")
+}
+
+func TestBareDurationUnitFromFile(t *testing.T) {
+	var config struct {
+		Timeout time.Duration
+	}
+	a := newTestAmalgam(t, &config, nil, WithBareDurationUnit(time.Second))
+	loadYAML(t, a, "timeout: 30\n")
+	if config.Timeout != 30*time.Second {
+		t.Errorf("Timeout = %v, want 30s", config.Timeout)
+	}
+}
+
+func TestDurationDisplayUnit(t *testing.T) {
+	var config struct {
+		Timeout time.Duration `amalgam:",default=1m30s,durationunit=s"`
+	}
+	a := newTestAmalgam(t, &config, []string{"--timeout", "2m"})
+	if got, want := a.flagSet.Lookup("timeout").DefValue, "90s"; got != want {
+		t.Errorf("help default = %q, want %q", got, want)
+	}
+	if usage := a.flagSet.FlagUsages(); !strings.Contains(usage, "(default 90s)") {
+		t.Errorf("help doesn't show the default in seconds:\n%s", usage)
+	}
+
+	loadYAML(t, a, "")
+	if config.Timeout != 2*time.Minute {
+		t.Errorf("Timeout = %v, want the flag parsed as a standard duration", config.Timeout)
+	}
+}
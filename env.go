@@ -0,0 +1,248 @@
+package amalgam
+
+import (
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// WithEnvSliceSeparator sets the separator used to split environment
+// variable values for slice fields (the default is a comma), eg. for values
+// which contain commas themselves.
+func WithEnvSliceSeparator(sep string) func(*Amalgam) {
+	return func(a *Amalgam) {
+		a.envSliceSeparator = sep
+	}
+}
+
+// WithMapSeparators sets the separators used to parse map fields given as a
+// single string, such as an environment variable: entrySep between the
+// entries, and pairSep between each key and value.  The defaults are a comma
+// and `=` (eg. `k1=v1,k2=v2`).
+func WithMapSeparators(entrySep, pairSep string) func(*Amalgam) {
+	return func(a *Amalgam) {
+		a.mapEntrySeparator, a.mapPairSeparator = entrySep, pairSep
+	}
+}
+
+// WithEnvNoSeparator derives the environment variable names without
+// separating camelCase words (eg. `APIKey` is read from `APIKEY` rather than
+// `API_KEY`), for legacy env conventions.  Nested fields are still separated
+// from their struct (eg. `API_KEY` for `API.Key`).
+func WithEnvNoSeparator() func(*Amalgam) {
+	return func(a *Amalgam) {
+		a.envNoSeparator = true
+	}
+}
+
+// WithStrictEnvPrefix ensures that only environment variables starting with
+// the env prefix populate the config, so that a stray variable (eg. `PORT`)
+// doesn't override a field.  Names given with the `env` tag option without
+// the prefix are ignored.  It requires WithEnvPrefix.
+func WithStrictEnvPrefix() func(*Amalgam) {
+	return func(a *Amalgam) {
+		a.strictEnvPrefix = true
+	}
+}
+
+// WithExplicitEnv reads each field only from its derived environment
+// variable (eg. `DATABASE_MAX_IDLE_CONNS`), and its `env` tag option, which
+// are bound explicitly, rather than also using viper's automatic env lookup
+// (eg. of `DATABASE_MAXIDLECONNS`).
+func WithExplicitEnv() func(*Amalgam) {
+	return func(a *Amalgam) {
+		a.explicitEnv = true
+	}
+}
+
+// exactEnvNames returns the names of the environment variables given by the
+// field's `env` tag option, separated by `|` (eg. `MYAPP_TOKEN|TOKEN`), in
+// order of precedence.  Names without the env prefix are ignored with
+// WithStrictEnvPrefix.
+func (a *Amalgam) exactEnvNames(field string) []string {
+	var names []string
+	for _, name := range strings.Split(a.fields[field].options["env"], "|") {
+		if name == "" || (a.strictEnvPrefix && !strings.HasPrefix(name, strings.ToUpper(a.envPrefix)+"_")) {
+			continue
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+// envVarName returns the name of the environment variable for a field.  This
+// joins the env prefix, the struct path and the field name, separating
+// camelCase words with underscores (eg. `Database.MaxIdleConns` becomes
+// `DATABASE_MAX_IDLE_CONNS`), unless WithEnvNoSeparator was given.
+func (a *Amalgam) envVarName(field string) string {
+	if a.envNoSeparator {
+		return a.legacyEnvVarName(field)
+	}
+
+	name := strings.Replace(defaultFlagNameFunc(field), "-", "_", -1)
+	if a.envPrefix != "" {
+		name = a.envPrefix + "_" + name
+	}
+	return strings.ToUpper(name)
+}
+
+// primaryEnvVarName returns the name of the environment variable which takes
+// precedence for a field, as shown in the help output and docs.
+func (a *Amalgam) primaryEnvVarName(field string) string {
+	if exact := a.exactEnvNames(field); len(exact) > 0 {
+		return exact[0]
+	}
+	return a.envVarName(field)
+}
+
+// legacyEnvVarName returns the name of the environment variable for a field
+// as derived by viper's automatic env, which doesn't separate camelCase words
+// (eg. `DATABASE_MAXIDLECONNS`).
+func (a *Amalgam) legacyEnvVarName(field string) string {
+	key := strings.ToLower(field)
+	if a.envPrefix != "" {
+		key = a.envPrefix + "_" + key
+	}
+	return strings.ToUpper(a.envKeyReplacer.Replace(key))
+}
+
+// envVarNames returns the names of the environment variables which populate
+// a field, in order of precedence.
+func (a *Amalgam) envVarNames(field string) []string {
+	names := a.exactEnvNames(field)
+	legacy, name := a.legacyEnvVarName(field), a.envVarName(field)
+	if legacy == name || a.explicitEnv {
+		return append(names, name)
+	}
+	return append(names, legacy, name)
+}
+
+// exactEnvValue returns the value of the first environment variable named by
+// the field's `env` tag option which is set.  viper applies its key replacer
+// to the names of bound variables (and only binds one), so these are looked
+// up directly instead.
+func (a *Amalgam) exactEnvValue(field string) (string, bool) {
+	if a.flagsOnly || a.flagOrSetValue(field) {
+		return "", false
+	}
+	for _, name := range a.exactEnvNames(field) {
+		if value, ok := os.LookupEnv(name); ok {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+// envValue returns the value of the environment variable which populates a
+// field, if any.
+func (a *Amalgam) envValue(field string) (string, bool) {
+	_, value, ok := a.envVar(field)
+	return value, ok
+}
+
+// envVar returns the name and value of the environment variable which
+// populates a field, if any.
+func (a *Amalgam) envVar(field string) (string, string, bool) {
+	if a.flagsOnly {
+		return "", "", false
+	}
+	for _, name := range a.envVarNames(field) {
+		if value, ok := os.LookupEnv(name); ok {
+			return name, value, true
+		}
+	}
+	return "", "", false
+}
+
+// ConsumedEnvVars returns the names of the environment variables which
+// supplied a value for a field in the last load, in sorted order, eg. for
+// auditing the environment an app depends on.  Variables overridden by a
+// flag aren't included, while those named by `defaultenv` are included when
+// they provided the field's default.
+func (a *Amalgam) ConsumedEnvVars() []string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	return append([]string(nil), a.consumedEnv...)
+}
+
+// consumedEnvVars returns the names of the environment variables which
+// supplied a value for a field, for ConsumedEnvVars.
+func (a *Amalgam) consumedEnvVars() []string {
+	if a.flagsOnly {
+		return nil
+	}
+
+	consumed := make(map[string]bool)
+	for _, field := range a.sortedFields() {
+		info := a.fields[field]
+		if info.excluded() || a.flagOrSetValue(field) {
+			continue
+		}
+		if name, _, ok := a.envVar(field); ok {
+			consumed[name] = true
+			continue
+		}
+		if _, file := a.fileValues[field]; file || a.inFile(field) {
+			continue
+		}
+		if name := info.options["defaultenv"]; name != "" {
+			if _, ok := os.LookupEnv(name); ok {
+				consumed[name] = true
+			}
+		}
+	}
+
+	names := make([]string, 0, len(consumed))
+	for name := range consumed {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// envDefault returns the value of the environment variable named by the
+// field's `defaultenv` tag option, which is used as its default in place of
+// the value in the config object.
+func envDefault(info fieldInfo) (string, bool) {
+	name := info.options["defaultenv"]
+	if name == "" {
+		return "", false
+	}
+	return os.LookupEnv(name)
+}
+
+// splitEnvSlice splits the raw setting for a slice field on the env slice
+// separator, if the setting came from the environment.  Other settings are
+// returned unchanged, to be split on commas by the decode hook.
+func (a *Amalgam) splitEnvSlice(field string, info fieldInfo, raw interface{}) (interface{}, error) {
+	t := info.value.Type()
+	if a.envSliceSeparator == "" || t.Kind() != reflect.Slice || t.Elem().Kind() == reflect.Uint8 {
+		return raw, nil
+	}
+
+	s, ok := raw.(string)
+	if !ok {
+		return raw, nil
+	}
+	if value, ok := a.envValue(field); !ok || value != s {
+		return raw, nil
+	}
+
+	list, err := splitList(s, a.envSliceSeparator)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]interface{}, len(list))
+	for i, item := range list {
+		items[i] = item
+	}
+	return items, nil
+}
+
+// inEnv reports whether the environment provided a value for the field.
+func (a *Amalgam) inEnv(field string) bool {
+	_, ok := a.envValue(field)
+	return ok
+}
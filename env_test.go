@@ -0,0 +1,238 @@
+package amalgam
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/spf13/pflag"
+)
+
+func TestNestedEnvVarNames(t *testing.T) {
+	var config struct {
+		Database struct {
+			Primary struct {
+				MaxIdleConns int
+			}
+		}
+		TLS struct {
+			CertFile string
+		}
+	}
+	defer setEnv("MYAPP_DATABASE_PRIMARY_MAX_IDLE_CONNS", "7")()
+	defer setEnv("MYAPP_TLS_CERT_FILE", "/etc/tls/cert.pem")()
+
+	a := newTestAmalgam(t, &config, nil, WithEnvPrefix("MYAPP"))
+	for field, want := range map[string]string{
+		"Database.Primary.MaxIdleConns": "MYAPP_DATABASE_PRIMARY_MAX_IDLE_CONNS",
+		"TLS.CertFile":                  "MYAPP_TLS_CERT_FILE",
+	} {
+		if got := a.envVarName(field); got != want {
+			t.Errorf("envVarName(%q) = %q, want %q", field, got, want)
+		}
+	}
+	loadYAML(t, a, "")
+
+	if config.Database.Primary.MaxIdleConns != 7 {
+		t.Errorf("Database.Primary.MaxIdleConns = %d, want 7", config.Database.Primary.MaxIdleConns)
+	}
+	if config.TLS.CertFile != "/etc/tls/cert.pem" {
+		t.Errorf("TLS.CertFile = %q, want /etc/tls/cert.pem", config.TLS.CertFile)
+	}
+}
+
+func TestEnvSliceSeparator(t *testing.T) {
+	var config struct {
+		Hosts []string
+		Ports []int
+	}
+	defer setEnv("HOSTS", "a,1;b,2")()
+	defer setEnv("PORTS", "80;443")()
+
+	a := newTestAmalgam(t, &config, nil, WithEnvSliceSeparator(";"))
+	loadYAML(t, a, "")
+
+	if !reflect.DeepEqual(config.Hosts, []string{"a,1", "b,2"}) {
+		t.Errorf("Hosts = %q, want the env value split on ;", config.Hosts)
+	}
+	if !reflect.DeepEqual(config.Ports, []int{80, 443}) {
+		t.Errorf("Ports = %v, want [80 443]", config.Ports)
+	}
+}
+
+func TestEnvNoSeparator(t *testing.T) {
+	var config struct {
+		APIKey   string
+		Database struct {
+			MaxConns int
+		}
+	}
+	defer setEnv("APIKEY", "secret")()
+	defer setEnv("API_KEY", "separated")()
+	defer setEnv("DATABASE_MAXCONNS", "9")()
+
+	a := newTestAmalgam(t, &config, nil, WithEnvNoSeparator())
+	loadYAML(t, a, "")
+
+	if config.APIKey != "secret" {
+		t.Errorf("APIKey = %q, want it bound to APIKEY", config.APIKey)
+	}
+	if config.Database.MaxConns != 9 {
+		t.Errorf("Database.MaxConns = %d, want it bound to DATABASE_MAXCONNS", config.Database.MaxConns)
+	}
+}
+
+func TestExactEnvName(t *testing.T) {
+	var config struct {
+		Namespace string `amalgam:",env=POD_NAMESPACE"`
+		PodIP     string `amalgam:",env=status.podIP"`
+	}
+	defer setEnv("POD_NAMESPACE", "kube-system")()
+	defer setEnv("status.podIP", "10.1.2.3")()
+
+	a := newTestAmalgam(t, &config, nil, WithEnvPrefix("MYAPP"))
+	loadYAML(t, a, "")
+
+	if config.Namespace != "kube-system" {
+		t.Errorf("Namespace = %q, want it bound to POD_NAMESPACE", config.Namespace)
+	}
+	if config.PodIP != "10.1.2.3" {
+		t.Errorf("PodIP = %q, want it bound to status.podIP", config.PodIP)
+	}
+}
+
+func TestStrictEnvPrefix(t *testing.T) {
+	var config struct {
+		Port int
+		Host string
+	}
+	defer setEnv("PORT", "1")()
+	defer setEnv("HOST", "stray")()
+	defer setEnv("MYAPP_PORT", "8080")()
+
+	a := newTestAmalgam(t, &config, nil, WithEnvPrefix("MYAPP"), WithStrictEnvPrefix())
+	loadYAML(t, a, "host: file\n")
+
+	if config.Port != 8080 {
+		t.Errorf("Port = %d, want MYAPP_PORT", config.Port)
+	}
+	if config.Host != "file" {
+		t.Errorf("Host = %q, want the unprefixed HOST ignored", config.Host)
+	}
+}
+
+func TestStrictEnvPrefixRequiresPrefix(t *testing.T) {
+	var config struct {
+		Port int
+	}
+	_, err := New(&config, WithFlagSet(pflag.NewFlagSet("test", pflag.ContinueOnError)), WithStrictEnvPrefix())
+	assertError(t, err, "strict env prefix requires an env prefix")
+}
+
+func TestExplicitEnv(t *testing.T) {
+	type explicitConfig struct {
+		Name     string
+		APIKey   string
+		Database struct {
+			MaxConns int
+		}
+		Labels map[string]string
+	}
+	var config explicitConfig
+	a := newTestAmalgam(t, &config, nil, WithEnvPrefix("MYAPP"), WithExplicitEnv())
+
+	vars := map[string]string{
+		"Name":              "MYAPP_NAME",
+		"APIKey":            "MYAPP_API_KEY",
+		"Database.MaxConns": "MYAPP_DATABASE_MAX_CONNS",
+		"Labels":            "MYAPP_LABELS",
+	}
+	for field, want := range vars {
+		if got := a.envVarNames(field); !reflect.DeepEqual(got, []string{want}) {
+			t.Errorf("envVarNames(%q) = %q, want only %s", field, got, want)
+		}
+	}
+
+	defer setEnv("MYAPP_NAME", "svc")()
+	defer setEnv("MYAPP_APIKEY", "legacy")()
+	defer setEnv("MYAPP_DATABASE_MAX_CONNS", "3")()
+	defer setEnv("MYAPP_LABELS", "team=core")()
+	loadYAML(t, a, "")
+
+	// MYAPP_APIKEY isn't bound, as it would be by viper's automatic env.
+	want := explicitConfig{Name: "svc", Labels: map[string]string{"team": "core"}}
+	want.Database.MaxConns = 3
+	if !reflect.DeepEqual(config, want) {
+		t.Errorf("got %+v, want %+v", config, want)
+	}
+}
+
+func TestDefaultEnv(t *testing.T) {
+	type regionConfig struct {
+		Region string `amalgam:",defaultenv=AWS_REGION"`
+	}
+	defer setEnv("AWS_REGION", "eu-west-1")()
+
+	var config regionConfig
+	a := newTestAmalgam(t, &config, nil)
+	loadYAML(t, a, "")
+	if config.Region != "eu-west-1" {
+		t.Errorf("Region = %q, want the AWS_REGION fallback", config.Region)
+	}
+
+	config = regionConfig{}
+	a = newTestAmalgam(t, &config, nil)
+	loadYAML(t, a, "region: us-east-1\n")
+	if config.Region != "us-east-1" {
+		t.Errorf("Region = %q, want the file over the fallback", config.Region)
+	}
+
+	config = regionConfig{}
+	defer setEnv("REGION", "ap-south-1")()
+	a = newTestAmalgam(t, &config, nil)
+	loadYAML(t, a, "")
+	if config.Region != "ap-south-1" {
+		t.Errorf("Region = %q, want the field's own env var over the fallback", config.Region)
+	}
+}
+
+func TestEnvFallbacks(t *testing.T) {
+	var config struct {
+		Token string `amalgam:",env=MYAPP_TOKEN|TOKEN"`
+	}
+	os.Unsetenv("MYAPP_TOKEN")
+	defer setEnv("TOKEN", "fallback")()
+
+	a := newTestAmalgam(t, &config, nil)
+	loadYAML(t, a, "")
+	if config.Token != "fallback" {
+		t.Errorf("Token = %q, want the second fallback's value", config.Token)
+	}
+
+	defer setEnv("MYAPP_TOKEN", "primary")()
+	a = newTestAmalgam(t, &config, nil)
+	loadYAML(t, a, "")
+	if config.Token != "primary" {
+		t.Errorf("Token = %q, want the first set var to win", config.Token)
+	}
+}
+
+func TestConsumedEnvVars(t *testing.T) {
+	var config struct {
+		Name  string
+		Port  int
+		Token string `amalgam:",env=API_TOKEN"`
+	}
+	defer setEnv("NAME", "svc")()
+	defer setEnv("API_TOKEN", "secret")()
+	defer setEnv("UNRELATED_SETTING", "x")()
+	os.Unsetenv("PORT")
+
+	a := newTestAmalgam(t, &config, nil)
+	loadYAML(t, a, "port: 8080\n")
+
+	want := []string{"API_TOKEN", "NAME"}
+	if got := a.ConsumedEnvVars(); !reflect.DeepEqual(got, want) {
+		t.Errorf("ConsumedEnvVars() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,99 @@
+package amalgam
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// EnvVars returns the environment variables which represent the resolved
+// config, keyed by name, eg. for writing a `.env` file or configuring a
+// subprocess.  Slices are joined with the env slice separator (a comma by
+// default), quoting items containing it.  Fields which can't be given by the
+// environment (eg. maps and slices of structs) are omitted, as are excluded
+// fields and nil pointers.
+func (a *Amalgam) EnvVars() map[string]string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	sep := a.envSliceSeparator
+	if sep == "" {
+		sep = ","
+	}
+
+	vars := make(map[string]string, len(a.fields))
+	for field, info := range a.fields {
+		if info.excluded() {
+			continue
+		}
+		v := a.fieldValue(info)
+		if !v.IsValid() {
+			continue
+		}
+
+		var value string
+		var ok bool
+		if info.options.has("inline") {
+			data, err := json.Marshal(v.Interface())
+			value, ok = string(data), err == nil
+		} else {
+			value, ok = formatEnvValue(info, v, sep)
+		}
+		if ok {
+			vars[a.primaryEnvVarName(field)] = value
+		}
+	}
+	return vars
+}
+
+// formatEnvValue formats a field value as it would be given by an environment
+// variable, reporting false for values which can't be.
+func formatEnvValue(info fieldInfo, v reflect.Value, sep string) (string, bool) {
+	t := v.Type()
+	switch {
+	case t == timeType:
+		return formatTime(info.timeLayout(), v.Interface().(time.Time)), true
+	case t == addrType:
+		if v.IsNil() {
+			return "", true
+		}
+		return v.Interface().(net.Addr).String(), true
+	case t == ipMaskType:
+		return v.Interface().(net.IPMask).String(), true
+	case isValueType(t):
+		ptr := reflect.New(t)
+		ptr.Elem().Set(v)
+		return formatValue(ptr), true
+	}
+	if base, _ := intBase(info); base != 0 {
+		return formatInt(v, base), true
+	}
+
+	switch t.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return fmt.Sprint(v.Interface()), true
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return "", false
+		}
+		items := make([]string, v.Len())
+		for i := range items {
+			item, ok := formatEnvValue(info, v.Index(i), sep)
+			if !ok {
+				return "", false
+			}
+			if strings.Contains(item, sep) || strings.Contains(item, `"`) {
+				item = `"` + strings.Replace(item, `"`, `""`, -1) + `"`
+			}
+			items[i] = item
+		}
+		return strings.Join(items, sep), true
+	}
+	return "", false
+}
@@ -0,0 +1,49 @@
+package amalgam
+
+import (
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type envVarsConfig struct {
+	Name     string
+	Port     int
+	Debug    bool
+	Timeout  time.Duration
+	Hosts    []string
+	Mode     os.FileMode `amalgam:",base=8"`
+	Database struct {
+		MaxConns int
+	}
+}
+
+func TestEnvVarsRoundTrip(t *testing.T) {
+	var config envVarsConfig
+	a := newTestAmalgam(t, &config, nil, WithEnvPrefix("MYAPP"), WithEnvSliceSeparator(";"))
+	loadYAML(t, a, "name: svc\nport: 8080\ndebug: true\ntimeout: 1m30s\nhosts: [a, \"b;c\"]\nmode: \"0o755\"\ndatabase:\n  maxconns: 5\n")
+
+	vars := a.EnvVars()
+	if got := vars["MYAPP_DATABASE_MAX_CONNS"]; got != "5" {
+		t.Errorf("MYAPP_DATABASE_MAX_CONNS = %q, want 5", got)
+	}
+	if got := vars["MYAPP_MODE"]; got != "0o755" {
+		t.Errorf("MYAPP_MODE = %q, want 0o755", got)
+	}
+	if got := vars["MYAPP_HOSTS"]; got != `a;"b;c"` {
+		t.Errorf("MYAPP_HOSTS = %q, want the hosts joined with ;", got)
+	}
+
+	for name, value := range vars {
+		os.Setenv(name, value)
+		defer os.Unsetenv(name)
+	}
+	var reloaded envVarsConfig
+	b := newTestAmalgam(t, &reloaded, nil, WithEnvPrefix("MYAPP"), WithEnvSliceSeparator(";"))
+	loadYAML(t, b, "")
+
+	if !reflect.DeepEqual(reloaded, config) {
+		t.Errorf("got %+v from the env vars, want %+v", reloaded, config)
+	}
+}
@@ -0,0 +1,50 @@
+package amalgam
+
+import (
+	"strings"
+)
+
+// ErrorList is a list of problems found with the config, which are reported
+// together rather than stopping at the first one.
+type ErrorList []error
+
+func (l ErrorList) Error() string {
+	msgs := make([]string, len(l))
+	for i, err := range l {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// err returns the list as an error, or nil if the list is empty.
+func (l ErrorList) err() error {
+	if len(l) == 0 {
+		return nil
+	}
+	return l
+}
+
+// NamedError is an error from an Amalgam given a name by WithName.  It wraps
+// the underlying error (eg. an ErrorList), prefixing its message with the
+// name.
+type NamedError struct {
+	Name string
+	Err  error
+}
+
+func (e *NamedError) Error() string {
+	return e.Name + ": " + e.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *NamedError) Unwrap() error {
+	return e.Err
+}
+
+// named wraps err in a NamedError if the Amalgam has a name.
+func (a *Amalgam) named(err error) error {
+	if err == nil || a.name == "" {
+		return err
+	}
+	return &NamedError{Name: a.name, Err: err}
+}
@@ -0,0 +1,55 @@
+package amalgam
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/spf13/pflag"
+)
+
+func TestNamedErrors(t *testing.T) {
+	var config struct {
+		Port int
+	}
+	a := newTestAmalgam(t, &config, nil, WithName("plugin config"))
+	assertError(t, a.Load(strings.NewReader("port: [1, 2]\n")), "plugin config: ")
+
+	a = newTestAmalgam(t, &config, []string{"--config", "/nonexistent/config.yaml"}, WithName("plugin config"))
+	assertError(t, a.LoadFile(), "plugin config: ")
+
+	_, err := New(&config,
+		WithFlagSet(pflag.NewFlagSet("test", pflag.ContinueOnError)),
+		WithName("plugin config"),
+		WithStrictEnvPrefix(),
+	)
+	assertError(t, err, "plugin config: strict env prefix requires an env prefix")
+
+	a = newTestAmalgam(t, &config, nil, WithName("plugin config"))
+	loadYAML(t, a, "port: 8080\n")
+	_, err = a.GetStringE("port")
+	assertError(t, err, `plugin config: config key "port" has value`)
+
+	a.Freeze()
+	err = a.Set("port", 9090)
+	assertError(t, err, "plugin config: config is frozen")
+	if !errors.Is(err, ErrFrozen) {
+		t.Errorf("errors.Is(%v, ErrFrozen) = false, want the NamedError unwrapped", err)
+	}
+	var named *NamedError
+	if !errors.As(err, &named) || named.Name != "plugin config" {
+		t.Errorf("errors.As(%v) didn't find a NamedError for the name", err)
+	}
+}
+
+func TestUnnamedErrors(t *testing.T) {
+	var config struct {
+		Port int
+	}
+	a := newTestAmalgam(t, &config, nil)
+	err := a.Load(strings.NewReader("port: [1, 2]\n"))
+	var named *NamedError
+	if err == nil || errors.As(err, &named) {
+		t.Errorf("got %v, want an error not wrapped in a NamedError", err)
+	}
+}
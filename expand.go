@@ -0,0 +1,72 @@
+package amalgam
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+)
+
+// WithEnvVarExpansion expands environment variables (`$VAR` or `${VAR}`) in
+// the values of string and []string fields after loading.  Undefined
+// variables expand to an empty string.
+func WithEnvVarExpansion() func(*Amalgam) {
+	return func(a *Amalgam) {
+		a.expandEnv = true
+	}
+}
+
+// WithStrictEnvVarExpansion is like WithEnvVarExpansion, but loading fails
+// if a value refers to an undefined variable.
+func WithStrictEnvVarExpansion() func(*Amalgam) {
+	return func(a *Amalgam) {
+		a.expandEnv = true
+		a.strictExpandEnv = true
+	}
+}
+
+// expandEnvVars expands the environment variables in the string fields of
+// the config object, skipping the fields in unchanged.
+func (a *Amalgam) expandEnvVars(unchanged map[string]bool) error {
+	var errs ErrorList
+	for _, field := range a.sortedFields() {
+		info := a.fields[field]
+		if info.excluded() || unchanged[field] {
+			continue
+		}
+		v := a.fieldValue(info)
+		if !v.IsValid() || !v.CanSet() {
+			continue
+		}
+
+		var undefined []string
+		switch {
+		case v.Kind() == reflect.String:
+			undefined = expandValue(v)
+		case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.String:
+			for i := 0; i < v.Len(); i++ {
+				undefined = append(undefined, expandValue(v.Index(i))...)
+			}
+		}
+		if a.strictExpandEnv && len(undefined) > 0 {
+			errs = append(errs, fmt.Errorf("%s: undefined environment variables %s", field, strings.Join(undefined, ", ")))
+		}
+	}
+
+	return errs.err()
+}
+
+// expandValue expands the environment variables in a string value, and
+// returns the names of any undefined variables.
+func expandValue(v reflect.Value) []string {
+	var undefined []string
+	expanded := os.Expand(v.String(), func(name string) string {
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			undefined = append(undefined, name)
+		}
+		return value
+	})
+	v.SetString(expanded)
+	return undefined
+}
@@ -0,0 +1,38 @@
+package amalgam
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEnvVarExpansion(t *testing.T) {
+	defer setEnv("AMALGAM_TEST_HOME", "/home/user")()
+	os.Unsetenv("Data: undefined environment variables AMALGAM_TEST_UNDEFINED")
+
+	var config struct {
+		Data  string
+		Paths []string
+	}
+	a := newTestAmalgam(t, &config, nil, WithEnvVarExpansion())
+	loadYAML(t, a, "data: ${AMALGAM_TEST_HOME}/data${AMALGAM_TEST_UNDEFINED}\npaths: [$AMALGAM_TEST_HOME/a, /b]\n")
+
+	if config.Data != "/home/user/data" {
+		t.Errorf("Data = %q, want the defined variable expanded and the undefined one empty", config.Data)
+	}
+	if want := []string{"/home/user/a", "/b"}; !reflect.DeepEqual(config.Paths, want) {
+		t.Errorf("Paths = %q, want %q", config.Paths, want)
+	}
+}
+
+func TestStrictEnvVarExpansion(t *testing.T) {
+	defer setEnv("AMALGAM_TEST_HOME", "/home/user")()
+	os.Unsetenv("Data: undefined environment variables AMALGAM_TEST_UNDEFINED")
+
+	var config struct {
+		Data string
+	}
+	a := newTestAmalgam(t, &config, nil, WithStrictEnvVarExpansion())
+	assertError(t, a.Load(strings.NewReader("data: ${AMALGAM_TEST_HOME}/${AMALGAM_TEST_UNDEFINED}\n")), "Data: undefined environment variables AMALGAM_TEST_UNDEFINED")
+}
@@ -0,0 +1,52 @@
+package amalgam
+
+import (
+	"github.com/spf13/viper"
+)
+
+// LoadFlagsOnly hydrates the config from the flags and default values only,
+// ignoring the environment and any config file, for tools which deliberately
+// don't read ambient config.  Later loads read them as usual.
+func (a *Amalgam) LoadFlagsOnly() error {
+	return a.named(a.loadFlagsOnly())
+}
+
+// loadFlagsOnly does the work of LoadFlagsOnly.
+func (a *Amalgam) loadFlagsOnly() error {
+	if err := a.parseFlags(); err != nil {
+		return err
+	}
+
+	a.mu.Lock()
+	v := a.viper
+	a.viper, a.flagsOnly = a.flagsViper(), true
+	err := a.loadLocked(configDoc{settings: make(map[string]interface{})})
+	a.viper, a.flagsOnly = v, false
+	a.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	a.notifyLoad()
+	return nil
+}
+
+// flagsViper returns a viper with the defaults and flags of the fields, but
+// without reading the environment.
+func (a *Amalgam) flagsViper() *viper.Viper {
+	v := viper.New()
+	for field, info := range a.fields {
+		if info.excluded() {
+			continue
+		}
+
+		v.SetDefault(field, copySlice(a.defaultInfo(field, info).value))
+		if value, ok := a.defaults[field]; ok {
+			v.SetDefault(field, value)
+		}
+		if flag := a.flagSet.Lookup(info.flagName); flag != nil && !info.options.has("filewins") {
+			v.BindPFlag(field, flag)
+		}
+	}
+	return v
+}
@@ -0,0 +1,46 @@
+package amalgam
+
+import (
+	"testing"
+)
+
+func TestLoadFlagsOnly(t *testing.T) {
+	var config struct {
+		Name string
+		Port int
+		Host string
+	}
+	config.Host = "localhost"
+	defer setEnv("NAME", "from-env")()
+	defer setEnv("HOST", "from-env")()
+
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+	path := writeFile(t, dir, "config.yaml", "name: from-file\nhost: from-file\n")
+
+	a := newTestAmalgam(t, &config, []string{"--config", path, "--port", "8080"})
+	if err := a.LoadFlagsOnly(); err != nil {
+		t.Fatal(err)
+	}
+
+	if config.Name != "" {
+		t.Errorf("Name = %q, want the env var and file ignored", config.Name)
+	}
+	if config.Host != "localhost" {
+		t.Errorf("Host = %q, want the default", config.Host)
+	}
+	if config.Port != 8080 {
+		t.Errorf("Port = %d, want the flag", config.Port)
+	}
+}
+
+func TestLoadFlagsOnlyParseError(t *testing.T) {
+	var config struct {
+		Port int
+	}
+	a := newTestAmalgam(t, &config, []string{"--port", "eighty"})
+	assertError(t, a.LoadFlagsOnly(), `invalid argument "eighty" for "--port"`)
+
+	a = newTestAmalgam(t, &config, []string{"--bogus"})
+	assertError(t, a.LoadFlagsOnly(), "unknown flag: --bogus")
+}
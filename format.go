@@ -0,0 +1,18 @@
+package amalgam
+
+import "strings"
+
+// RegisterFormat adds support for config files in a custom format (eg. HCL)
+// to the Amalgam, by the file extension (without the dot, eg. `hcl`).  The
+// unmarshal function converts a document into a settings map, which is used
+// as the settings from a YAML, JSON or TOML file would be.  Registering a
+// built-in format replaces it.  It must be called before loading the config.
+func (a *Amalgam) RegisterFormat(ext string, unmarshal func([]byte) (map[string]interface{}, error)) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.formats == nil {
+		a.formats = make(map[string]func([]byte) (map[string]interface{}, error))
+	}
+	a.formats[strings.ToLower(strings.TrimPrefix(ext, "."))] = unmarshal
+}
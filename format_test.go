@@ -0,0 +1,48 @@
+package amalgam
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// parseKeyValues parses a trivial format of `key = value` lines, with dotted
+// keys for nested settings.
+func parseKeyValues(data []byte) (map[string]interface{}, error) {
+	settings := make(map[string]interface{})
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line == "" {
+			continue
+		}
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 {
+			return nil, errors.New("expected key = value")
+		}
+		setPath(settings, keyPath(strings.TrimSpace(parts[0])), strings.TrimSpace(parts[1]))
+	}
+	return settings, nil
+}
+
+func TestRegisterFormat(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+	path := writeFile(t, dir, "app.kv", "name = svc\ndatabase.port = 5432\n")
+
+	var config struct {
+		Name     string
+		Database struct {
+			Port int
+		}
+	}
+	a := newTestAmalgam(t, &config, []string{"--config", path}, WithConfigType(""))
+	a.RegisterFormat("kv", parseKeyValues)
+	if err := a.LoadFile(); err != nil {
+		t.Fatal(err)
+	}
+	if config.Name != "svc" || config.Database.Port != 5432 {
+		t.Errorf("got %+v, want the custom format's settings", config)
+	}
+
+	writeFile(t, dir, "app.kv", "name\n")
+	assertError(t, a.LoadFile(), "expected key = value")
+}
@@ -0,0 +1,71 @@
+package amalgam
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// LoadKeyFromFile merges the value at the key (eg. `services.billing`) in the
+// file into the same key of the loaded config, leaving the rest of the
+// config as it was, eg. where each service maintains its own section.  The
+// file's value takes precedence over the config loaded before it.
+func (a *Amalgam) LoadKeyFromFile(key, path string) error {
+	return a.named(a.loadKeyFromFile(key, path))
+}
+
+// loadKeyFromFile does the work of LoadKeyFromFile.
+func (a *Amalgam) loadKeyFromFile(key, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return a.loadDocs(configDoc{previous: true}, configDoc{r: f, format: a.formatFor(path), name: path, key: key})
+}
+
+// LoadKeyFromReader hydrates the config from the value at the key (eg.
+// `config`, or `deploy.config`) of the document read from r in the format,
+// eg. where the config is embedded in a larger JSON document.  The value is
+// loaded as if it were the whole config file, and it's an error if the key is
+// missing.
+func (a *Amalgam) LoadKeyFromReader(r io.Reader, format, key string) error {
+	return a.named(a.loadDocs(configDoc{r: r, format: format, root: key}))
+}
+
+// subtreeAt returns settings (and raw settings, if given) containing only the
+// value at the key.
+func subtreeAt(key string, settings, raw map[string]interface{}) (map[string]interface{}, map[string]interface{}, error) {
+	value, ok := lookupPath(settings, keyPath(key))
+	if !ok {
+		return nil, nil, fmt.Errorf("key %q not found", key)
+	}
+	subtree := make(map[string]interface{})
+	setPath(subtree, keyPath(key), value)
+
+	var rawSubtree map[string]interface{}
+	if raw != nil {
+		rawSubtree = make(map[string]interface{})
+		if value, keys, ok := lookupRawPath(raw, strings.Split(key, ".")); ok {
+			setPath(rawSubtree, keys, value)
+		}
+	}
+
+	return subtree, rawSubtree, nil
+}
+
+// rootAt returns the value at the key in the settings, which must be a map,
+// for use as the settings of a whole document.
+func rootAt(key string, settings map[string]interface{}) (map[string]interface{}, error) {
+	value, _, ok := lookupRawPath(settings, strings.Split(key, "."))
+	if !ok {
+		return nil, fmt.Errorf("key %q not found", key)
+	}
+	root, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("key %q is not a map", key)
+	}
+	return root, nil
+}
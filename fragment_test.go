@@ -0,0 +1,72 @@
+package amalgam
+
+import (
+	"strings"
+	"testing"
+)
+
+type servicesConfig struct {
+	Name     string
+	Services struct {
+		Billing struct {
+			Port    int
+			Workers int
+		}
+		Search struct {
+			Port int
+		}
+	}
+}
+
+func TestLoadKeyFromFile(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+	billing := writeFile(t, dir, "billing.yaml", "name: ignored\nservices:\n  billing:\n    port: 9001\n  search:\n    port: 1\n")
+	search := writeFile(t, dir, "search.yaml", "services:\n  search:\n    port: 9002\n  billing:\n    workers: 1\n")
+
+	var config servicesConfig
+	a := newTestAmalgam(t, &config, nil)
+	loadYAML(t, a, "name: monorepo\nservices:\n  billing:\n    port: 8001\n    workers: 4\n")
+
+	if err := a.LoadKeyFromFile("services.billing", billing); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.LoadKeyFromFile("services.search", search); err != nil {
+		t.Fatal(err)
+	}
+
+	if config.Name != "monorepo" {
+		t.Errorf("Name = %q, want keys outside the fragments unchanged", config.Name)
+	}
+	if config.Services.Billing.Port != 9001 || config.Services.Billing.Workers != 4 {
+		t.Errorf("Billing = %+v, want the billing fragment merged", config.Services.Billing)
+	}
+	if config.Services.Search.Port != 9002 {
+		t.Errorf("Search = %+v, want the search fragment merged", config.Services.Search)
+	}
+
+	assertError(t, a.LoadKeyFromFile("services.missing", billing), `key "services.missing" not found`)
+}
+
+func TestLoadKeyFromReader(t *testing.T) {
+	doc := `{
+		"deploy": {
+			"region": "eu-west-1",
+			"config": {"name": "billing", "services": {"billing": {"port": 9001}}}
+		},
+		"name": "ignored"
+	}`
+
+	var config servicesConfig
+	a := newTestAmalgam(t, &config, nil)
+	if err := a.LoadKeyFromReader(strings.NewReader(doc), "json", "deploy.config"); err != nil {
+		t.Fatal(err)
+	}
+	if config.Name != "billing" || config.Services.Billing.Port != 9001 {
+		t.Errorf("got %+v, want the subtree at deploy.config", config)
+	}
+
+	a = newTestAmalgam(t, &config, nil, WithName("app"))
+	assertError(t, a.LoadKeyFromReader(strings.NewReader(doc), "json", "deploy.missing"), `app: key "deploy.missing" not found`)
+	assertError(t, a.LoadKeyFromReader(strings.NewReader(doc), "json", "deploy.region"), `key "deploy.region" is not a map`)
+}
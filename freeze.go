@@ -0,0 +1,19 @@
+package amalgam
+
+import (
+	"errors"
+)
+
+// ErrFrozen is returned when attempting to modify the config after Freeze has
+// been called.
+var ErrFrozen = errors.New("config is frozen")
+
+// Freeze marks the config as read-only, so that subsequent calls to Set or to
+// any of the Load methods return ErrFrozen.  The config object itself can
+// still be read as normal.
+func (a *Amalgam) Freeze() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.frozen = true
+}
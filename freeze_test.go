@@ -0,0 +1,33 @@
+package amalgam
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestFreeze(t *testing.T) {
+	var config struct {
+		Name string
+	}
+	a := newTestAmalgam(t, &config, nil)
+	loadYAML(t, a, "name: before\n")
+	a.Freeze()
+
+	for name, mutate := range map[string]func() error{
+		"Set":     func() error { return a.Set("name", "after") },
+		"Load":    func() error { return a.Load(strings.NewReader("name: after\n")) },
+		"LoadMap": func() error { return a.LoadMap(map[string]interface{}{"name": "after"}) },
+	} {
+		if err := mutate(); !errors.Is(err, ErrFrozen) {
+			t.Errorf("%s after Freeze: got error %v, want ErrFrozen", name, err)
+		}
+	}
+
+	if config.Name != "before" {
+		t.Errorf("Name = %q, want it unchanged after Freeze", config.Name)
+	}
+	if got, err := a.GetStringE("name"); err != nil || got != "before" {
+		t.Errorf("GetStringE(name) = %q, %v, want before", got, err)
+	}
+}
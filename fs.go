@@ -0,0 +1,28 @@
+//go:build go1.16
+// +build go1.16
+
+package amalgam
+
+import (
+	"io/fs"
+)
+
+// LoadFS hydrates the config from the named file in the filesystem (eg. an
+// embed.FS).  The format is the one given by WithConfigType, or else inferred
+// from the file extension.
+//
+// This is only available with Go 1.16 or later.
+func (a *Amalgam) LoadFS(fsys fs.FS, name string) error {
+	return a.named(a.loadFS(fsys, name))
+}
+
+// loadFS does the work of LoadFS.
+func (a *Amalgam) loadFS(fsys fs.FS, name string) error {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return a.load(f, a.formatFor(name))
+}
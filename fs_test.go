@@ -0,0 +1,28 @@
+//go:build go1.16
+// +build go1.16
+
+package amalgam
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoadFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"config/app.json": {Data: []byte(`{"name": "embedded", "port": 8080}`)},
+	}
+	var config struct {
+		Name string
+		Port int
+	}
+	a := newTestAmalgam(t, &config, nil, WithConfigType(""))
+	if err := a.LoadFS(fsys, "config/app.json"); err != nil {
+		t.Fatal(err)
+	}
+	if config.Name != "embedded" || config.Port != 8080 {
+		t.Errorf("got %+v, want the embedded config", config)
+	}
+
+	assertError(t, a.LoadFS(fsys, "config/missing.json"), "missing.json")
+}
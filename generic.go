@@ -0,0 +1,24 @@
+//go:build go1.18
+// +build go1.18
+
+package amalgam
+
+// Load allocates a config object of type T (a struct, which may contain
+// nested structs), creates an Amalgam for it with the options, and loads the
+// config with LoadFile.  It returns the populated config object, and the
+// Amalgam for reloading it or inspecting the sources:
+//
+//	config, a, err := amalgam.Load[Config](amalgam.WithEnvPrefix("app"))
+//
+// This needs Go 1.18 or later.
+func Load[T any](options ...Option) (*T, *Amalgam, error) {
+	config := new(T)
+	a, err := New(config, options...)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := a.LoadFile(); err != nil {
+		return nil, a, err
+	}
+	return config, a, nil
+}
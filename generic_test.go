@@ -0,0 +1,36 @@
+//go:build go1.18
+// +build go1.18
+
+package amalgam
+
+import (
+	"testing"
+
+	"github.com/spf13/pflag"
+)
+
+func TestGenericLoad(t *testing.T) {
+	type serverConfig struct {
+		Name   string
+		Server struct {
+			Port int `amalgam:",default=8080"`
+		}
+	}
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+	path := writeFile(t, dir, "config.yaml", "name: svc\n")
+
+	config, a, err := Load[serverConfig](
+		WithFlagSet(pflag.NewFlagSet("test", pflag.ContinueOnError)),
+		WithArgs([]string{"--config", path, "--server-port", "9090"}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a == nil {
+		t.Fatal("Load returned a nil Amalgam")
+	}
+	if config.Name != "svc" || config.Server.Port != 9090 {
+		t.Errorf("got %+v, want the file's name and the flag's port", *config)
+	}
+}
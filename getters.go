@@ -0,0 +1,154 @@
+package amalgam
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// getE returns the resolved value of the config key (eg. `api.timeout`), or
+// an error if it isn't set.  The values of fields are read from the config
+// object, so that they include the sources resolved outside viper (eg. value
+// files, interpolation and transforms), while other keys (eg. the entries of
+// a map field) are read from viper.
+func (a *Amalgam) getE(key string) (reflect.Value, error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	if _, info, ok := a.lookupField(key); ok && !info.excluded() {
+		if v := a.fieldValue(info); v.IsValid() {
+			// Copy the value, as the caller reads it without the lock.
+			return reflect.ValueOf(v.Interface()), nil
+		}
+	}
+	if value := a.viper.Get(key); value != nil {
+		return reflect.ValueOf(value), nil
+	}
+	return reflect.Value{}, a.named(fmt.Errorf("config key %q is not set", key))
+}
+
+// typeError returns the error for a config key whose value can't be used as
+// the type.
+func (a *Amalgam) typeError(key string, v reflect.Value, typeName string) error {
+	return a.named(fmt.Errorf("config key %q has value %#v of type %s, not %s", key, v.Interface(), v.Type(), typeName))
+}
+
+// GetStringE returns the value of the config key as a string, or an error if
+// it isn't set or isn't a string.
+func (a *Amalgam) GetStringE(key string) (string, error) {
+	v, err := a.getE(key)
+	if err != nil {
+		return "", err
+	}
+	if v.Kind() != reflect.String {
+		return "", a.typeError(key, v, "string")
+	}
+	return v.String(), nil
+}
+
+// GetBoolE returns the value of the config key as a bool, or an error if it
+// isn't set or isn't a bool (or a string parsing as one).
+func (a *Amalgam) GetBoolE(key string) (bool, error) {
+	v, err := a.getE(key)
+	if err != nil {
+		return false, err
+	}
+	switch v.Kind() {
+	case reflect.Bool:
+		return v.Bool(), nil
+	case reflect.String:
+		if b, err := strconv.ParseBool(v.String()); err == nil {
+			return b, nil
+		}
+	}
+	return false, a.typeError(key, v, "bool")
+}
+
+// GetInt64E returns the value of the config key as an int64, or an error if
+// it isn't set or isn't an integer (or a string parsing as one).
+func (a *Amalgam) GetInt64E(key string) (int64, error) {
+	return a.intE(key, "int64")
+}
+
+// intE returns the value of the config key as an int64, reporting type
+// errors against the type name.
+func (a *Amalgam) intE(key, typeName string) (int64, error) {
+	v, err := a.getE(key)
+	if err != nil {
+		return 0, err
+	}
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if v.Uint() <= math.MaxInt64 {
+			return int64(v.Uint()), nil
+		}
+	case reflect.Float32, reflect.Float64:
+		// math.MaxInt64 rounds up to 2^63 as a float64, which is out of
+		// range.
+		if f := v.Float(); f == math.Trunc(f) && f >= math.MinInt64 && f < math.MaxInt64 {
+			return int64(f), nil
+		}
+	case reflect.String:
+		if i, err := strconv.ParseInt(v.String(), 0, 64); err == nil {
+			return i, nil
+		}
+	}
+	return 0, a.typeError(key, v, typeName)
+}
+
+// GetIntE returns the value of the config key as an int, or an error if it
+// isn't set or isn't an integer (or a string parsing as one) in range.
+func (a *Amalgam) GetIntE(key string) (int, error) {
+	i, err := a.intE(key, "int")
+	if err != nil {
+		return 0, err
+	}
+	if int64(int(i)) != i {
+		return 0, a.named(fmt.Errorf("config key %q has value %d, out of range for int", key, i))
+	}
+	return int(i), nil
+}
+
+// GetFloat64E returns the value of the config key as a float64, or an error
+// if it isn't set or isn't a number (or a string parsing as one).
+func (a *Amalgam) GetFloat64E(key string) (float64, error) {
+	v, err := a.getE(key)
+	if err != nil {
+		return 0, err
+	}
+	switch v.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), nil
+	case reflect.String:
+		if f, err := strconv.ParseFloat(v.String(), 64); err == nil {
+			return f, nil
+		}
+	}
+	return 0, a.typeError(key, v, "float64")
+}
+
+// GetDurationE returns the value of the config key as a time.Duration, or an
+// error if it isn't set or isn't a duration (or a string parsing as one).
+func (a *Amalgam) GetDurationE(key string) (time.Duration, error) {
+	v, err := a.getE(key)
+	if err != nil {
+		return 0, err
+	}
+	switch {
+	case v.Type() == durationType:
+		return time.Duration(v.Int()), nil
+	case v.Kind() == reflect.String:
+		if d, err := time.ParseDuration(v.String()); err == nil {
+			return d, nil
+		}
+	}
+	return 0, a.typeError(key, v, "time.Duration")
+}
@@ -0,0 +1,113 @@
+package amalgam
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+type getterConfig struct {
+	Name    string
+	Port    int
+	Debug   bool
+	Ratio   float64
+	Timeout time.Duration
+	Labels  map[string]string
+}
+
+func TestGettersE(t *testing.T) {
+	var config getterConfig
+	a := newTestAmalgam(t, &config, []string{"--port", "8080"})
+	loadYAML(t, a, "name: svc\ndebug: true\nratio: 0.5\ntimeout: 5s\nlabels:\n  team: core\n")
+
+	if s, err := a.GetStringE("name"); err != nil || s != "svc" {
+		t.Errorf("GetStringE(name) = %q, %v", s, err)
+	}
+	if i, err := a.GetIntE("port"); err != nil || i != 8080 {
+		t.Errorf("GetIntE(port) = %d, %v", i, err)
+	}
+	if i, err := a.GetInt64E("Port"); err != nil || i != 8080 {
+		t.Errorf("GetInt64E(Port) = %d, %v", i, err)
+	}
+	if b, err := a.GetBoolE("debug"); err != nil || !b {
+		t.Errorf("GetBoolE(debug) = %v, %v", b, err)
+	}
+	if f, err := a.GetFloat64E("ratio"); err != nil || f != 0.5 {
+		t.Errorf("GetFloat64E(ratio) = %v, %v", f, err)
+	}
+	if d, err := a.GetDurationE("timeout"); err != nil || d != 5*time.Second {
+		t.Errorf("GetDurationE(timeout) = %v, %v", d, err)
+	}
+	if s, err := a.GetStringE("labels.team"); err != nil || s != "core" {
+		t.Errorf("GetStringE(labels.team) = %q, %v", s, err)
+	}
+}
+
+func TestGettersEMissing(t *testing.T) {
+	var config getterConfig
+	a := newTestAmalgam(t, &config, nil)
+	loadYAML(t, a, "")
+
+	_, err := a.GetStringE("missing")
+	assertError(t, err, `config key "missing" is not set`)
+	_, err = a.GetIntE("labels.missing")
+	assertError(t, err, `config key "labels.missing" is not set`)
+}
+
+func TestGettersEMistyped(t *testing.T) {
+	var config getterConfig
+	a := newTestAmalgam(t, &config, nil)
+	loadYAML(t, a, "name: svc\nport: 80\n")
+
+	for name, get := range map[string]func() error{
+		"GetIntE(name)":      func() error { _, err := a.GetIntE("name"); return err },
+		"GetBoolE(port)":     func() error { _, err := a.GetBoolE("port"); return err },
+		"GetStringE(port)":   func() error { _, err := a.GetStringE("port"); return err },
+		"GetDurationE(name)": func() error { _, err := a.GetDurationE("name"); return err },
+		"GetFloat64E(name)":  func() error { _, err := a.GetFloat64E("name"); return err },
+	} {
+		if err := get(); err == nil || !strings.Contains(err.Error(), "config key") {
+			t.Errorf("%s: got error %v, want a type error", name, err)
+		}
+	}
+	_, err := a.GetIntE("name")
+	assertError(t, err, `config key "name" has value "svc" of type string, not int`)
+}
+
+func TestGetInt64EFloatRange(t *testing.T) {
+	var config getterConfig
+	a := newTestAmalgam(t, &config, nil)
+
+	// 2^63 is a float64, but one too large for an int64, while the floats
+	// either side of it are in range.
+	for _, test := range []struct {
+		ratio string
+		want  int64
+		err   string
+	}{
+		{ratio: "9223372036854774784.0", want: 9223372036854774784},
+		{ratio: "-9223372036854775808.0", want: -9223372036854775808},
+		{ratio: "9223372036854775808.0", err: "of type float64, not int64"},
+	} {
+		loadYAML(t, a, "ratio: "+test.ratio+"\n")
+		got, err := a.GetInt64E("ratio")
+		if test.err != "" {
+			assertError(t, err, test.err)
+			continue
+		}
+		if err != nil || got != test.want {
+			t.Errorf("GetInt64E(ratio) for %s = %d, %v, want %d", test.ratio, got, err, test.want)
+		}
+	}
+}
+
+func TestGettersEAgreeWithConfig(t *testing.T) {
+	var config getterConfig
+	upper := func(v interface{}) (interface{}, error) { return strings.ToUpper(v.(string)), nil }
+	a := newTestAmalgam(t, &config, nil, WithFieldTransform("name", upper))
+	loadYAML(t, a, "name: svc\n")
+
+	if s, err := a.GetStringE("name"); err != nil || s != config.Name {
+		t.Errorf("GetStringE(name) = %q, %v, want the config object's %q", s, err, config.Name)
+	}
+}
@@ -0,0 +1,57 @@
+package amalgam
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// LoadGlob hydrates the config from the files matching the pattern (eg. a
+// drop-in directory such as `/etc/myapp/conf.d/*.yaml`).  The files are
+// merged in lexicographical order, with later files taking precedence.  If
+// nothing matches, the config is loaded from the other sources only.
+func (a *Amalgam) LoadGlob(pattern string) error {
+	return a.named(a.loadGlob(pattern))
+}
+
+// loadGlob does the work of LoadGlob.
+func (a *Amalgam) loadGlob(pattern string) error {
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return err
+	}
+	sort.Strings(matches)
+
+	docs := make([]configDoc, 0, len(matches))
+	for _, name := range matches {
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		docs = append(docs, configDoc{r: f, format: a.formatFor(name), name: name})
+	}
+	if len(docs) == 0 {
+		docs = append(docs, configDoc{})
+	}
+
+	return a.loadDocs(docs...)
+}
+
+// mergeSettings merges src into a copy of dst, merging nested maps and
+// replacing any other values.
+func mergeSettings(dst, src map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{}, len(dst)+len(src))
+	for key, value := range dst {
+		merged[key] = value
+	}
+	for key, value := range src {
+		if m, ok := value.(map[string]interface{}); ok {
+			existing, _ := merged[key].(map[string]interface{})
+			value = mergeSettings(existing, m)
+		}
+		merged[key] = value
+	}
+	return merged
+}
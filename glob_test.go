@@ -0,0 +1,48 @@
+package amalgam
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadGlob(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+	writeFile(t, dir, "20-override.yaml", "port: 9090\n")
+	writeFile(t, dir, "10-base.yaml", "name: base\nport: 8080\nlevel: info\n")
+	writeFile(t, dir, "30-level.yaml", "level: debug\n")
+	writeFile(t, dir, "notes.txt", "name: ignored\n")
+
+	var config struct {
+		Name  string
+		Port  int
+		Level string
+	}
+	a := newTestAmalgam(t, &config, []string{"--level", "warn"}, WithConfigType(""))
+	if err := a.LoadGlob(filepath.Join(dir, "*.yaml")); err != nil {
+		t.Fatal(err)
+	}
+
+	if config.Name != "base" || config.Port != 9090 {
+		t.Errorf("got %+v, want later files to override earlier ones", config)
+	}
+	if config.Level != "warn" {
+		t.Errorf("Level = %q, want the flag to override the files", config.Level)
+	}
+}
+
+func TestLoadGlobNoMatches(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	var config struct {
+		Name string
+	}
+	a := newTestAmalgam(t, &config, []string{"--name", "flag"})
+	if err := a.LoadGlob(filepath.Join(dir, "*.yaml")); err != nil {
+		t.Fatal(err)
+	}
+	if config.Name != "flag" {
+		t.Errorf("Name = %q, want the flags still loaded", config.Name)
+	}
+}
@@ -0,0 +1,48 @@
+package amalgam
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+)
+
+// gzipExt is the extension of gzip-compressed config files, which is ignored
+// when inferring the format (eg. `config.yaml.gz` is YAML).
+const gzipExt = ".gz"
+
+// gzipMagic is the header at the start of gzip-compressed data.
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// gunzip returns a reader of the config in r, decompressing it if it's
+// gzip-compressed, which is detected by peeking at its first bytes.
+func gunzip(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(len(gzipMagic))
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if !bytes.Equal(magic, gzipMagic) {
+		return br, nil
+	}
+
+	zr, err := gzip.NewReader(br)
+	if err != nil {
+		return nil, fmt.Errorf("invalid gzip-compressed config: %v", err)
+	}
+	return gzipReader{zr}, nil
+}
+
+// gzipReader reads gzip-compressed config, reporting corrupt data as such.
+type gzipReader struct {
+	r *gzip.Reader
+}
+
+func (g gzipReader) Read(p []byte) (int, error) {
+	n, err := g.r.Read(p)
+	if err != nil && err != io.EOF {
+		err = fmt.Errorf("invalid gzip-compressed config: %v", err)
+	}
+	return n, err
+}
@@ -0,0 +1,53 @@
+package amalgam
+
+import (
+	"bytes"
+	"compress/gzip"
+	"testing"
+)
+
+func gzipData(t *testing.T, s string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write([]byte(s)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestGzippedConfigFile(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	for _, name := range []string{"config.yaml.gz", "config.yaml"} {
+		path := writeFile(t, dir, name, gzipData(t, "name: compressed\nhosts: [a, b]\n"))
+		var config struct {
+			Name  string
+			Hosts []string
+		}
+		a := newTestAmalgam(t, &config, []string{"--config", path}, WithConfigType(""))
+		if err := a.LoadFile(); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if config.Name != "compressed" || len(config.Hosts) != 2 {
+			t.Errorf("%s: got %+v, want the decompressed YAML", name, config)
+		}
+	}
+}
+
+func TestCorruptGzippedConfigFile(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	data := gzipData(t, "name: compressed\n")
+	path := writeFile(t, dir, "config.yaml.gz", data[:len(data)/2])
+	var config struct {
+		Name string
+	}
+	a := newTestAmalgam(t, &config, []string{"--config", path}, WithConfigType(""))
+	assertError(t, a.LoadFile(), "invalid gzip-compressed config")
+}
@@ -0,0 +1,187 @@
+package amalgam
+
+import (
+	"encoding/csv"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/mitchellh/mapstructure"
+)
+
+// WithDecoderTagName sets the struct tag used to match config keys to fields
+// when decoding (eg. "json", to reuse existing JSON field names), in place of
+// the `mapstructure` tag.  The fields are named by the tag throughout, so the
+// flags and environment variables are derived from it too.
+func WithDecoderTagName(name string) func(*Amalgam) {
+	return func(a *Amalgam) {
+		a.decoderTagName = name
+	}
+}
+
+// decoderConfig returns the config used to decode settings into result.  It
+// matches the viper defaults, apart from the decode hook, tag name and
+// zeroing fields.
+func (a *Amalgam) decoderConfig(result interface{}) *mapstructure.DecoderConfig {
+	return &mapstructure.DecoderConfig{
+		Result:           result,
+		TagName:          a.decoderTagName,
+		WeaklyTypedInput: true,
+		DecodeHook:       a.decodeHook(),
+		// On reload, replace maps and slices rather than merging into
+		// them, so that removed entries don't linger.
+		ZeroFields: a.reloading,
+	}
+}
+
+// decodeHook returns the hook used to convert settings into the types of the
+// config object fields.  Conversions for specific types must come before the
+// slice conversion, as some of those types (eg. net.IP) are slices themselves.
+func (a *Amalgam) decodeHook() mapstructure.DecodeHookFunc {
+	return mapstructure.ComposeDecodeHookFunc(
+		mapstructure.StringToTimeDurationHookFunc(),
+		mapstructure.StringToIPHookFunc(),
+		stringToIPMaskHookFunc(),
+		stringToTimeHookFunc(time.RFC3339),
+		stringToNumberHookFunc(),
+		stringToValueHookFunc(),
+		stringToMapHookFunc(a.mapSeparators()),
+		stringToSliceHookFunc(","),
+	)
+}
+
+// mapSeparators returns the separators between the entries of a map given as
+// a string, and between each key and value.
+func (a *Amalgam) mapSeparators() (string, string) {
+	entrySep, pairSep := ",", "="
+	if a.mapEntrySeparator != "" {
+		entrySep = a.mapEntrySeparator
+	}
+	if a.mapPairSeparator != "" {
+		pairSep = a.mapPairSeparator
+	}
+	return entrySep, pairSep
+}
+
+// stringToMapHookFunc converts strings such as `k1=v1,k2=v2` (eg. from an
+// environment variable) into maps, splitting the entries on entrySep and
+// each key from its value on pairSep.
+func stringToMapHookFunc(entrySep, pairSep string) mapstructure.DecodeHookFuncType {
+	return func(f reflect.Type, t reflect.Type, data interface{}) (interface{}, error) {
+		if f.Kind() != reflect.String || t.Kind() != reflect.Map || t.Key().Kind() != reflect.String {
+			return data, nil
+		}
+
+		return splitMap(data.(string), entrySep, pairSep)
+	}
+}
+
+// splitMap splits a string such as `k1=v1,k2=v2` into a map, splitting the
+// entries on entrySep and each key from its value on pairSep.
+func splitMap(raw, entrySep, pairSep string) (map[string]string, error) {
+	m := make(map[string]string)
+	if raw == "" {
+		return m, nil
+	}
+	for _, entry := range strings.Split(raw, entrySep) {
+		idx := strings.Index(entry, pairSep)
+		if idx <= 0 {
+			return nil, fmt.Errorf("invalid map entry %q, expected key%svalue", entry, pairSep)
+		}
+		m[strings.TrimSpace(entry[:idx])] = strings.TrimSpace(entry[idx+len(pairSep):])
+	}
+	return m, nil
+}
+
+// stringToIPMaskHookFunc converts strings to a net.IPMask, in either dotted
+// (255.255.255.0) or hex (ffffff00) form.
+func stringToIPMaskHookFunc() mapstructure.DecodeHookFuncType {
+	return func(f reflect.Type, t reflect.Type, data interface{}) (interface{}, error) {
+		if f.Kind() != reflect.String || t != ipMaskType {
+			return data, nil
+		}
+
+		return parseIPMask(data.(string))
+	}
+}
+
+// stringToSliceHookFunc splits strings into slices on sep.  Unlike the
+// mapstructure equivalent, it strips the surrounding brackets used by pflag
+// when rendering the value of a slice flag.
+func stringToSliceHookFunc(sep string) mapstructure.DecodeHookFuncType {
+	return func(f reflect.Type, t reflect.Type, data interface{}) (interface{}, error) {
+		if f.Kind() != reflect.String || t.Kind() != reflect.Slice {
+			return data, nil
+		}
+
+		return splitList(data.(string), sep)
+	}
+}
+
+// splitList splits a string into a list on sep, stripping the surrounding
+// brackets used by pflag when rendering the value of a slice flag.  Items
+// may be quoted CSV-style (eg. `"a,b",c`) to include the separator.
+func splitList(raw, sep string) ([]string, error) {
+	if strings.HasPrefix(raw, "[") && strings.HasSuffix(raw, "]") {
+		raw = raw[1 : len(raw)-1]
+	}
+	if raw == "" {
+		return []string{}, nil
+	}
+
+	comma, size := utf8.DecodeRuneInString(sep)
+	if !strings.Contains(raw, `"`) || size != len(sep) {
+		return strings.Split(raw, sep), nil
+	}
+
+	r := csv.NewReader(strings.NewReader(raw))
+	r.Comma = comma
+	list, err := r.Read()
+	if err != nil {
+		return nil, fmt.Errorf("invalid list %q: %v", raw, err)
+	}
+	return list, nil
+}
+
+// convertField converts a raw setting for a field, using the field's tag
+// options, before it is decoded into the config object.
+func (a *Amalgam) convertField(info fieldInfo, raw interface{}) (interface{}, error) {
+	if info.options.has("inline") {
+		return convertInline(info.value.Type(), raw)
+	}
+	if info.value.Type() == addrType {
+		return convertAddr(info, raw)
+	}
+
+	if a.strictBools && info.value.Kind() == reflect.Bool {
+		return convertBool(raw)
+	}
+	if base, err := intBase(info); err == nil && base != 0 {
+		return convertInt(info, raw, base)
+	}
+
+	if info.value.Type() == durationType {
+		if unit, err := a.durationUnit(info); err == nil && unit != 0 {
+			return convertBareDuration(raw, unit)
+		}
+	}
+
+	// A map of times given as a string (eg. from an environment variable)
+	// is split here, so that its times are parsed with the layout too.
+	if s, ok := raw.(string); ok && info.value.Kind() == reflect.Map && info.value.Type().Elem() == timeType {
+		entrySep, pairSep := a.mapSeparators()
+		m, err := splitMap(s, entrySep, pairSep)
+		if err != nil {
+			return nil, err
+		}
+		settings := make(map[string]interface{}, len(m))
+		for key, value := range m {
+			settings[key] = value
+		}
+		raw = settings
+	}
+
+	return convertTimes(info.value.Type(), info.timeLayout(), raw)
+}
@@ -0,0 +1,174 @@
+package amalgam
+
+import (
+	"fmt"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// ruleAction is a TextUnmarshaler, to check the decode hooks for value types
+// apply within slices of structs.
+type ruleAction string
+
+func (r *ruleAction) UnmarshalText(text []byte) error {
+	switch s := strings.ToLower(string(text)); s {
+	case "allow", "deny":
+		*r = ruleAction(s)
+		return nil
+	}
+	return fmt.Errorf("unknown action %q", text)
+}
+
+type RuleConfig struct {
+	Name    string
+	Action  ruleAction
+	Source  net.IP
+	Timeout time.Duration
+	Ports   []int
+}
+
+func TestSliceOfStructsFromFile(t *testing.T) {
+	var config struct {
+		Rules []RuleConfig
+	}
+	a := newTestAmalgam(t, &config, nil)
+	if a.flagSet.Lookup("rules") != nil {
+		t.Error("got a --rules flag for a slice of structs")
+	}
+	loadYAML(t, a, `
+rules:
+  - name: internal
+    action: ALLOW
+    source: 10.0.0.1
+    timeout: 5s
+    ports: 80,443
+  - name: rest
+    action: deny
+    source: 192.168.1.1
+    timeout: 1m
+    ports: [22]
+`)
+
+	want := []RuleConfig{
+		{Name: "internal", Action: "allow", Source: net.ParseIP("10.0.0.1"), Timeout: 5 * time.Second, Ports: []int{80, 443}},
+		{Name: "rest", Action: "deny", Source: net.ParseIP("192.168.1.1"), Timeout: time.Minute, Ports: []int{22}},
+	}
+	if !reflect.DeepEqual(config.Rules, want) {
+		t.Errorf("Rules = %+v, want %+v", config.Rules, want)
+	}
+}
+
+func TestSliceOfStructsDecodeError(t *testing.T) {
+	var config struct {
+		Rules []RuleConfig
+	}
+	a := newTestAmalgam(t, &config, nil)
+	assertError(t, a.Load(strings.NewReader("rules:\n  - name: bad\n    action: maybe\n")), `unknown action "maybe"`)
+}
+
+func TestQuotedSliceValues(t *testing.T) {
+	for _, test := range []struct {
+		arg  string
+		want []string
+	}{
+		{`"a,b",c`, []string{"a,b", "c"}},
+		{`a,b`, []string{"a", "b"}},
+		{`"x"`, []string{"x"}},
+	} {
+		var config struct {
+			Tags []string
+		}
+		a := newTestAmalgam(t, &config, []string{"--tags", test.arg})
+		loadYAML(t, a, "")
+		if !reflect.DeepEqual(config.Tags, test.want) {
+			t.Errorf("--tags %s: got %q, want %q", test.arg, config.Tags, test.want)
+		}
+	}
+}
+
+func TestQuotedSliceValuesFromEnv(t *testing.T) {
+	var config struct {
+		Tags []string
+	}
+	defer setEnv("TAGS", `"a,b",c`)()
+
+	a := newTestAmalgam(t, &config, nil)
+	loadYAML(t, a, "")
+	if want := []string{"a,b", "c"}; !reflect.DeepEqual(config.Tags, want) {
+		t.Errorf("Tags = %q, want %q", config.Tags, want)
+	}
+
+	defer setEnv("TAGS", `"a,b`)()
+	a = newTestAmalgam(t, &config, nil)
+	assertError(t, a.Load(strings.NewReader("")), "invalid list")
+}
+
+func TestMapFromEnv(t *testing.T) {
+	var config struct {
+		Labels map[string]string
+		Limits map[string]int
+	}
+	defer setEnv("LABELS", "team=core, tier = web")()
+	defer setEnv("LIMITS", "cpu=2,memory=512")()
+
+	a := newTestAmalgam(t, &config, nil)
+	loadYAML(t, a, "")
+
+	if want := map[string]string{"team": "core", "tier": "web"}; !reflect.DeepEqual(config.Labels, want) {
+		t.Errorf("Labels = %v, want %v", config.Labels, want)
+	}
+	if want := map[string]int{"cpu": 2, "memory": 512}; !reflect.DeepEqual(config.Limits, want) {
+		t.Errorf("Limits = %v, want %v", config.Limits, want)
+	}
+}
+
+func TestMapFromEnvSeparators(t *testing.T) {
+	var config struct {
+		Labels map[string]string
+	}
+	defer setEnv("LABELS", "team:core;query:a=b,c")()
+
+	a := newTestAmalgam(t, &config, nil, WithMapSeparators(";", ":"))
+	loadYAML(t, a, "")
+
+	if want := map[string]string{"team": "core", "query": "a=b,c"}; !reflect.DeepEqual(config.Labels, want) {
+		t.Errorf("Labels = %v, want %v", config.Labels, want)
+	}
+}
+
+func TestMapFromEnvMalformed(t *testing.T) {
+	var config struct {
+		Labels map[string]string
+	}
+	defer setEnv("LABELS", "team=core,oops")()
+
+	a := newTestAmalgam(t, &config, nil)
+	err := a.Load(strings.NewReader(""))
+	assertError(t, err, "Labels")
+	assertError(t, err, `invalid map entry "oops", expected key=value`)
+}
+
+func TestDecoderTagName(t *testing.T) {
+	var config struct {
+		MaxConns int    `json:"max_conns"`
+		Endpoint string `json:"api_endpoint"`
+		Ignored  string `json:"-"`
+	}
+	a := newTestAmalgam(t, &config, []string{"--api-endpoint", "http://flag"}, WithDecoderTagName("json"), WithConfigType("json"))
+	if err := a.Load(strings.NewReader(`{"max_conns": 7, "api_endpoint": "http://file", "ignored": "x"}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	if config.MaxConns != 7 {
+		t.Errorf("MaxConns = %d, want the json-tagged key", config.MaxConns)
+	}
+	if config.Endpoint != "http://flag" {
+		t.Errorf("Endpoint = %q, want the flag named from the json tag", config.Endpoint)
+	}
+	if config.Ignored != "" {
+		t.Errorf("Ignored = %q, want a - tagged field skipped", config.Ignored)
+	}
+}
@@ -0,0 +1,103 @@
+package amalgam
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// includeKey is the top-level key listing the files a config file includes.
+const includeKey = "include"
+
+// WithIncludes processes a top-level `include` list in config files, merging
+// the listed files before the file's own settings, so that the including
+// file takes precedence.  Relative paths are resolved against the directory
+// of the including file, and included files may include others in turn.
+func WithIncludes() func(*Amalgam) {
+	return func(a *Amalgam) {
+		a.includes = true
+	}
+}
+
+// readIncludes merges the files included by the named config file with its
+// settings (and raw settings), recording the sources of secret fields for
+// each file.  The paths of the files being read are tracked in visiting, to
+// detect circular includes.
+func (a *Amalgam) readIncludes(name string, settings, raw map[string]interface{}, secretSources map[string]string, visiting map[string]bool) (map[string]interface{}, map[string]interface{}, error) {
+	value, ok := settings[includeKey]
+	delete(settings, includeKey)
+	delete(raw, includeKey)
+	if !ok {
+		a.recordSecretSources(secretSources, name, settings)
+		return settings, raw, nil
+	}
+
+	var paths []string
+	switch v := value.(type) {
+	case string:
+		paths = []string{v}
+	case []interface{}:
+		for _, item := range v {
+			path, ok := item.(string)
+			if !ok {
+				return nil, nil, fmt.Errorf("invalid include %v, expected a path", item)
+			}
+			paths = append(paths, path)
+		}
+	default:
+		return nil, nil, fmt.Errorf("invalid include %v, expected a list of paths", value)
+	}
+
+	abs, err := filepath.Abs(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	if visiting == nil {
+		visiting = make(map[string]bool)
+	}
+	visiting[abs] = true
+	defer delete(visiting, abs)
+
+	included := make(map[string]interface{})
+	var includedRaw map[string]interface{}
+	for _, path := range paths {
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(filepath.Dir(name), path)
+		}
+		if abs, err := filepath.Abs(path); err != nil {
+			return nil, nil, err
+		} else if visiting[abs] {
+			return nil, nil, fmt.Errorf("circular include of %s", path)
+		}
+
+		docSettings, docRaw, err := a.readInclude(path, secretSources, visiting)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %v", path, err)
+		}
+		included = a.mergeDocs(included, docSettings)
+		if docRaw != nil {
+			includedRaw = a.mergeDocs(includedRaw, docRaw)
+		}
+	}
+
+	a.recordSecretSources(secretSources, name, settings)
+	if raw != nil {
+		raw = a.mergeDocs(includedRaw, raw)
+	}
+	return a.mergeDocs(included, settings), raw, nil
+}
+
+// readInclude reads an included config file, with its own includes.
+func (a *Amalgam) readInclude(path string, secretSources map[string]string, visiting map[string]bool) (map[string]interface{}, map[string]interface{}, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	settings, raw, err := a.readDoc(configDoc{r: f, format: a.formatFor(path), name: path})
+	if err != nil {
+		return nil, nil, err
+	}
+	return a.readIncludes(path, settings, raw, secretSources, visiting)
+}
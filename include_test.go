@@ -0,0 +1,63 @@
+package amalgam
+
+import (
+	"testing"
+)
+
+type includeConfig struct {
+	Name     string
+	Port     int
+	Database struct {
+		Host string
+		Port int
+	}
+}
+
+func TestIncludes(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+	writeFile(t, dir, "server.yaml", "name: included\nport: 8080\n")
+	writeFile(t, dir, "conf.d/db.yaml", "database:\n  host: db.local\n  port: 5432\n")
+	main := writeFile(t, dir, "config.yaml", "include:\n  - server.yaml\n  - conf.d/db.yaml\nname: main\n")
+
+	var config includeConfig
+	a := newTestAmalgam(t, &config, []string{"--config", main}, WithConfigType(""), WithIncludes())
+	if err := a.LoadFile(); err != nil {
+		t.Fatal(err)
+	}
+
+	if config.Name != "main" {
+		t.Errorf("Name = %q, want the main file over its includes", config.Name)
+	}
+	if config.Port != 8080 || config.Database.Host != "db.local" || config.Database.Port != 5432 {
+		t.Errorf("got %+v, want the included files merged", config)
+	}
+}
+
+func TestCircularInclude(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+	writeFile(t, dir, "a.yaml", "include: [b.yaml]\nname: a\n")
+	writeFile(t, dir, "b.yaml", "include: [a.yaml]\nport: 1\n")
+	main := writeFile(t, dir, "config.yaml", "include: [a.yaml]\n")
+
+	var config includeConfig
+	a := newTestAmalgam(t, &config, []string{"--config", main}, WithConfigType(""), WithIncludes())
+	assertError(t, a.LoadFile(), "circular include of")
+}
+
+func TestIncludeErrors(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	for doc, want := range map[string]string{
+		"include: [missing.yaml]\n": "missing.yaml",
+		"include: {a: b}\n":         "expected a list of paths",
+		"include: [1]\n":            "invalid include 1, expected a path",
+	} {
+		main := writeFile(t, dir, "config.yaml", doc)
+		var config includeConfig
+		a := newTestAmalgam(t, &config, []string{"--config", main}, WithConfigType(""), WithIncludes())
+		assertError(t, a.LoadFile(), want)
+	}
+}
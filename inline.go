@@ -0,0 +1,52 @@
+package amalgam
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// inlineFormat returns the format of the serialized values accepted for a
+// field, from the `inline` tag option (eg. `inline=json`), or an empty string
+// if the option wasn't given.
+func inlineFormat(info fieldInfo) (string, error) {
+	if !info.options.has("inline") {
+		return "", nil
+	}
+
+	switch format := info.options["inline"]; format {
+	case "json":
+		return format, nil
+	default:
+		return "", fmt.Errorf("unsupported inline format %q", format)
+	}
+}
+
+// inlineDefault returns the flag default for an inline field, serialized in
+// the field's inline format.
+func inlineDefault(info fieldInfo) string {
+	b, err := json.Marshal(info.value.Interface())
+	if err != nil || string(b) == "null" {
+		return ""
+	}
+	return string(b)
+}
+
+// convertInline parses a raw string setting for an inline field into a value
+// of the field's type.  Other settings (eg. nested keys from the config file)
+// are returned unchanged.
+func convertInline(t reflect.Type, raw interface{}) (interface{}, error) {
+	s, ok := raw.(string)
+	if !ok {
+		return raw, nil
+	}
+
+	v := reflect.New(t)
+	if s == "" {
+		return v.Elem().Interface(), nil
+	}
+	if err := json.Unmarshal([]byte(s), v.Interface()); err != nil {
+		return nil, fmt.Errorf("invalid inline json: %v", err)
+	}
+	return v.Elem().Interface(), nil
+}
@@ -0,0 +1,48 @@
+package amalgam
+
+import (
+	"strings"
+	"testing"
+)
+
+type inlineBackend struct {
+	Host  string
+	Port  int
+	Pools []string
+}
+
+func TestInlineJSONFromEnv(t *testing.T) {
+	var config struct {
+		Backend inlineBackend `amalgam:",inline=json"`
+	}
+	defer setEnv("BACKEND", `{"host": "db.local", "port": 5432, "pools": ["a", "b"]}`)()
+
+	a := newTestAmalgam(t, &config, nil)
+	loadYAML(t, a, "")
+
+	if config.Backend.Host != "db.local" || config.Backend.Port != 5432 || len(config.Backend.Pools) != 2 {
+		t.Errorf("Backend = %+v, want the decoded JSON", config.Backend)
+	}
+}
+
+func TestInlineJSONFromFile(t *testing.T) {
+	var config struct {
+		Backend inlineBackend `amalgam:",inline=json"`
+	}
+	a := newTestAmalgam(t, &config, []string{"--backend", `{"port": 6000}`})
+	loadYAML(t, a, "backend:\n  host: file.local\n")
+
+	if config.Backend.Port != 6000 {
+		t.Errorf("Backend = %+v, want the flag's JSON", config.Backend)
+	}
+}
+
+func TestInlineJSONInvalid(t *testing.T) {
+	var config struct {
+		Backend inlineBackend `amalgam:",inline=json"`
+	}
+	defer setEnv("BACKEND", `{"host": `)()
+
+	a := newTestAmalgam(t, &config, nil)
+	assertError(t, a.Load(strings.NewReader("")), "Backend")
+}
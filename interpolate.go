@@ -0,0 +1,139 @@
+package amalgam
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// keyRefPattern matches a reference to another key (eg. `${host}`).
+var keyRefPattern = regexp.MustCompile(`\$\{([^}]+)\}`)
+
+// WithKeyInterpolation resolves references to other keys (eg.
+// `http://${host}:${port}`) in the values of string and []string fields after
+// loading, using their resolved values.  Referenced values may contain
+// references in turn, but circular references are an error, as are
+// references to undefined keys.
+func WithKeyInterpolation() func(*Amalgam) {
+	return func(a *Amalgam) {
+		a.interpolateKeys = true
+	}
+}
+
+// WithLenientKeyInterpolation is like WithKeyInterpolation, but references
+// to undefined keys are left as they are (eg. to be expanded by
+// WithEnvVarExpansion).
+func WithLenientKeyInterpolation() func(*Amalgam) {
+	return func(a *Amalgam) {
+		a.interpolateKeys = true
+		a.lenientInterpolation = true
+	}
+}
+
+// interpolator resolves the key references in the string fields, tracking
+// the fields being resolved to catch circular references.  The fields in a
+// reported cycle are tracked so that it's only reported once.
+type interpolator struct {
+	a        *Amalgam
+	resolved map[string]string
+	visiting []string
+	cyclic   map[string]bool
+}
+
+// interpolate resolves the key references in the string fields of the config
+// object, skipping the fields in unchanged.
+func (a *Amalgam) interpolate(unchanged map[string]bool) error {
+	in := &interpolator{a: a, resolved: make(map[string]string), cyclic: make(map[string]bool)}
+
+	var errs ErrorList
+	for _, field := range a.sortedFields() {
+		info := a.fields[field]
+		if info.excluded() || unchanged[field] || in.cyclic[field] {
+			continue
+		}
+		v := a.fieldValue(info)
+		if !v.IsValid() || !v.CanSet() {
+			continue
+		}
+
+		switch {
+		case v.Kind() == reflect.String:
+			s, err := in.resolveField(field)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			v.SetString(s)
+		case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.String:
+			for i := 0; i < v.Len(); i++ {
+				s, err := in.expand(field, v.Index(i).String())
+				if err != nil {
+					errs = append(errs, err)
+					break
+				}
+				v.Index(i).SetString(s)
+			}
+		}
+	}
+
+	return errs.err()
+}
+
+// resolveField returns the value of a string field with its references
+// resolved.
+func (in *interpolator) resolveField(field string) (string, error) {
+	if s, ok := in.resolved[field]; ok {
+		return s, nil
+	}
+	for i, visiting := range in.visiting {
+		if visiting == field {
+			cycle := append(append([]string(nil), in.visiting[i:]...), field)
+			for _, f := range cycle {
+				in.cyclic[f] = true
+			}
+			return "", fmt.Errorf("circular key reference %s", strings.Join(cycle, " -> "))
+		}
+	}
+
+	in.visiting = append(in.visiting, field)
+	s, err := in.expand(field, in.a.fieldValue(in.a.fields[field]).String())
+	in.visiting = in.visiting[:len(in.visiting)-1]
+	if err != nil {
+		return "", err
+	}
+
+	in.resolved[field] = s
+	return s, nil
+}
+
+// expand resolves the references in a value of the field.  References to
+// non-string fields are replaced by their values formatted as with fmt.
+func (in *interpolator) expand(field, s string) (string, error) {
+	var err error
+	expanded := keyRefPattern.ReplaceAllStringFunc(s, func(ref string) string {
+		if err != nil {
+			return ref
+		}
+
+		name, info, ok := in.a.lookupField(ref[2 : len(ref)-1])
+		if !ok || info.excluded() {
+			if !in.a.lenientInterpolation {
+				err = fmt.Errorf("%s: undefined key reference %s", field, ref)
+			}
+			return ref
+		}
+
+		v := in.a.fieldValue(info)
+		switch {
+		case !v.IsValid():
+			return ""
+		case v.Kind() == reflect.String:
+			var value string
+			value, err = in.resolveField(name)
+			return value
+		}
+		return fmt.Sprint(v.Interface())
+	})
+	return expanded, err
+}
@@ -0,0 +1,41 @@
+package amalgam
+
+import (
+	"strings"
+	"testing"
+)
+
+type interpolateConfig struct {
+	Host    string
+	Port    int
+	BaseURL string
+	Health  string
+}
+
+func TestKeyInterpolation(t *testing.T) {
+	var config interpolateConfig
+	a := newTestAmalgam(t, &config, []string{"--port", "9090"}, WithKeyInterpolation())
+	loadYAML(t, a, "host: example.com\nport: 80\nbaseurl: http://${host}:${port}\nhealth: ${baseurl}/health\n")
+
+	if config.BaseURL != "http://example.com:9090" {
+		t.Errorf("BaseURL = %q, want both references resolved", config.BaseURL)
+	}
+	if config.Health != "http://example.com:9090/health" {
+		t.Errorf("Health = %q, want the nested reference resolved", config.Health)
+	}
+}
+
+func TestKeyInterpolationErrors(t *testing.T) {
+	var config interpolateConfig
+	a := newTestAmalgam(t, &config, nil, WithKeyInterpolation())
+	assertError(t, a.Load(strings.NewReader("baseurl: http://${missing}\n")), "BaseURL: undefined key reference ${missing}")
+
+	a = newTestAmalgam(t, &config, nil, WithKeyInterpolation())
+	assertError(t, a.Load(strings.NewReader("baseurl: ${health}\nhealth: ${baseurl}\n")), "circular key reference")
+
+	a = newTestAmalgam(t, &config, nil, WithLenientKeyInterpolation())
+	loadYAML(t, a, "baseurl: http://${missing}\n")
+	if config.BaseURL != "http://${missing}" {
+		t.Errorf("BaseURL = %q, want the undefined reference left as it is", config.BaseURL)
+	}
+}
@@ -0,0 +1,91 @@
+package amalgam
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// WithLayeredFiles loads the config from a base file (eg. the shipped
+// `config.default.yaml`), merged with an optional override file (eg. the
+// user's `config.yaml`), when no config file is given.  The base file must
+// exist, while a missing override file is skipped.  The environment and flags
+// take precedence over both.
+func WithLayeredFiles(base, override string) func(*Amalgam) {
+	return func(a *Amalgam) {
+		a.baseFile, a.overrideFile = base, override
+	}
+}
+
+// WithEnvConfigFile loads the config from a base file (eg. `config.yaml`),
+// merged with the file for the environment named by an environment variable
+// (eg. `config.staging.yaml` for `APP_ENV=staging`), when no config file is
+// given.  The base file must exist, while a missing environment-specific
+// file is skipped.
+func WithEnvConfigFile(baseName, envVar string) func(*Amalgam) {
+	return func(a *Amalgam) {
+		a.baseFile, a.envConfigVar = baseName, envVar
+	}
+}
+
+// layeredFiles returns the optional files merged over the base file, for
+// openConfigFiles.
+func (a *Amalgam) layeredFiles() []string {
+	var files []string
+	if a.overrideFile != "" {
+		files = append(files, a.configPath(a.overrideFile))
+	}
+	if env := os.Getenv(a.envConfigVar); a.envConfigVar != "" && env != "" {
+		base := a.configPath(a.baseFile)
+		ext := filepath.Ext(base)
+		files = append(files, strings.TrimSuffix(base, ext)+"."+env+ext)
+	}
+	return files
+}
+
+// loadLayered loads the base and override files given by WithLayeredFiles.
+// The override file is the one written by Persist.
+func (a *Amalgam) loadLayered() error {
+	docs, closeFiles, err := a.openConfigFiles(a.configPath(a.baseFile), a.layeredFiles()...)
+	if err != nil {
+		return err
+	}
+	defer closeFiles()
+
+	if err := a.loadDocs(docs...); err != nil {
+		return err
+	}
+	a.loadedFile, a.loadedLayered = a.configPath(a.baseFile), true
+	if a.overrideFile != "" {
+		a.loadedFile = a.configPath(a.overrideFile)
+	}
+
+	return nil
+}
+
+// openConfigFiles opens the required config file and those optional files
+// which exist, returning their documents in order, and a function closing
+// them.
+func (a *Amalgam) openConfigFiles(required string, optional ...string) ([]configDoc, func(), error) {
+	var files []*os.File
+	closeFiles := func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}
+
+	var docs []configDoc
+	for i, name := range append([]string{required}, optional...) {
+		f, err := os.Open(name)
+		if i > 0 && os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			closeFiles()
+			return nil, nil, err
+		}
+		files = append(files, f)
+		docs = append(docs, configDoc{r: f, format: a.formatFor(name), name: name})
+	}
+	return docs, closeFiles, nil
+}
@@ -0,0 +1,79 @@
+package amalgam
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+type layeredConfig struct {
+	Name  string
+	Port  int
+	Level string
+}
+
+func TestLayeredFiles(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+	base := writeFile(t, dir, "config.default.yaml", "name: default\nport: 8080\nlevel: info\n")
+	override := filepath.Join(dir, "config.yaml")
+
+	// Only the base file.
+	var config layeredConfig
+	a := newTestAmalgam(t, &config, []string{"--level", "warn"}, WithConfigType(""), WithLayeredFiles(base, override))
+	if err := a.LoadFile(); err != nil {
+		t.Fatal(err)
+	}
+	if config != (layeredConfig{"default", 8080, "warn"}) {
+		t.Errorf("got %+v, want the base file with the flag on top", config)
+	}
+
+	// The base and override files.
+	writeFile(t, dir, "config.yaml", "port: 9090\nlevel: debug\n")
+	config = layeredConfig{}
+	a = newTestAmalgam(t, &config, []string{"--level", "warn"}, WithConfigType(""), WithLayeredFiles(base, override))
+	if err := a.LoadFile(); err != nil {
+		t.Fatal(err)
+	}
+	if config != (layeredConfig{"default", 9090, "warn"}) {
+		t.Errorf("got %+v, want the override merged over the base file", config)
+	}
+}
+
+func TestLayeredFilesMissingBase(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+	base := filepath.Join(dir, "config.default.yaml")
+	override := writeFile(t, dir, "config.yaml", "port: 9090\n")
+
+	var config layeredConfig
+	a := newTestAmalgam(t, &config, nil, WithConfigType(""), WithLayeredFiles(base, override))
+	assertError(t, a.LoadFile(), "open "+base)
+}
+
+func TestEnvConfigFile(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+	base := writeFile(t, dir, "config.yaml", "name: base\nport: 8080\nlevel: info\n")
+	writeFile(t, dir, "config.staging.yaml", "port: 9090\n")
+
+	for _, test := range []struct {
+		env  string
+		want layeredConfig
+	}{
+		{"staging", layeredConfig{"base", 9090, "info"}},
+		{"production", layeredConfig{"base", 8080, "info"}},
+		{"", layeredConfig{"base", 8080, "info"}},
+	} {
+		func() {
+			defer setEnv("APP_ENV", test.env)()
+			var config layeredConfig
+			a := newTestAmalgam(t, &config, nil, WithConfigType(""), WithEnvConfigFile(base, "APP_ENV"))
+			if err := a.LoadFile(); err != nil {
+				t.Fatal(err)
+			}
+			if config != test.want {
+				t.Errorf("APP_ENV=%s: got %+v, want %+v", test.env, config, test.want)
+			}
+		}()
+	}
+}
@@ -0,0 +1,51 @@
+package amalgam
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Lint returns warnings about the config definition and the loaded config,
+// for developers to review: problems with the field tags (as reported by
+// WithStrictDefinition), the use of deprecated fields, keys in the config file
+// which don't match a field (as rejected by WithStrictKeys), and secret or
+// value file fields left empty.  Unlike validation, these don't prevent
+// loading.
+func (a *Amalgam) Lint() []string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	var warnings []string
+	for _, field := range a.sortedFields() {
+		info := a.fields[field]
+		if info.excluded() {
+			continue
+		}
+		for _, err := range a.checkField(field, info) {
+			warnings = append(warnings, fmt.Sprintf("%s: %v", field, err))
+		}
+	}
+
+	warnings = append(warnings, a.warnings...)
+
+	unknown := a.unknownKeys(a.fileSettings, "")
+	sort.Strings(unknown)
+	for _, key := range unknown {
+		warnings = append(warnings, fmt.Sprintf("config key %s doesn't match a field", key))
+	}
+
+	for _, field := range a.sortedFields() {
+		info := a.fields[field]
+		if info.excluded() || !isZero(a.fieldValue(info)) {
+			continue
+		}
+		switch {
+		case info.options.has("secret"):
+			warnings = append(warnings, fmt.Sprintf("%s: secret field is empty", field))
+		case info.options.has("valuefile"):
+			warnings = append(warnings, fmt.Sprintf("%s: value file field is empty", field))
+		}
+	}
+
+	return warnings
+}
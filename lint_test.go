@@ -0,0 +1,42 @@
+package amalgam
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLint(t *testing.T) {
+	var config struct {
+		Name     string
+		Password string `amalgam:",secret"`
+		Timeout  int    `amalgam:",layout=2006"`
+		OldName  string `amalgam:",deprecated=use name"`
+		Wait     time.Duration
+	}
+	a := newTestAmalgam(t, &config, nil)
+	loadYAML(t, a, "name: svc\noldname: legacy\nnmae: typo\n")
+
+	want := []string{
+		"Timeout: tag option layout is only supported for time fields",
+		"config key oldname is deprecated: use name",
+		"config key nmae doesn't match a field",
+		"Password: secret field is empty",
+	}
+	if got := a.Lint(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Lint() = %q, want %q", got, want)
+	}
+}
+
+func TestLintClean(t *testing.T) {
+	var config struct {
+		Name     string
+		Password string `amalgam:",secret"`
+	}
+	a := newTestAmalgam(t, &config, nil)
+	loadYAML(t, a, "name: svc\npassword: hunter2\n")
+
+	if got := a.Lint(); len(got) != 0 {
+		t.Errorf("Lint() = %q, want no warnings", got)
+	}
+}
@@ -0,0 +1,61 @@
+package amalgam
+
+import (
+	"fmt"
+	"strings"
+)
+
+// LoadMap hydrates the config from a settings map, as if it were the config
+// file, eg. for tests.  Keys may be nested maps, or dotted paths (eg.
+// `api.endpoint`), and are matched case-insensitively.  The environment and
+// flags still take precedence.
+func (a *Amalgam) LoadMap(m map[string]interface{}) error {
+	return a.named(a.loadDocs(configDoc{settings: expandKeys(m)}))
+}
+
+// expandKeys returns a copy of the settings map with dotted keys expanded
+// into nested maps.
+func expandKeys(m map[string]interface{}) map[string]interface{} {
+	expanded := make(map[string]interface{}, len(m))
+	for key, value := range m {
+		if nested, ok := settingsMap(value); ok {
+			value = expandKeys(nested)
+		}
+
+		path := strings.Split(key, ".")
+		for i := len(path) - 1; i > 0; i-- {
+			value = map[string]interface{}{path[i]: value}
+		}
+		expanded = mergeSettings(expanded, map[string]interface{}{path[0]: value})
+	}
+	return expanded
+}
+
+// settingsMap returns the value as a map[string]interface{}, converting the
+// keys of a map[interface{}]interface{}.
+func settingsMap(value interface{}) (map[string]interface{}, bool) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return v, true
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			m[fmt.Sprint(key)] = item
+		}
+		return m, true
+	}
+	return nil, false
+}
+
+// lowercaseKeys returns a copy of the settings map with the keys lowercased,
+// as viper keys them.
+func lowercaseKeys(m map[string]interface{}) map[string]interface{} {
+	lowered := make(map[string]interface{}, len(m))
+	for key, value := range m {
+		if nested, ok := value.(map[string]interface{}); ok {
+			value = lowercaseKeys(nested)
+		}
+		lowered[strings.ToLower(key)] = value
+	}
+	return lowered
+}
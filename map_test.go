@@ -0,0 +1,43 @@
+package amalgam
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLoadMap(t *testing.T) {
+	var config struct {
+		Name     string
+		Database struct {
+			Host    string
+			Port    int
+			Timeout time.Duration
+			Replica struct {
+				Host string
+			}
+		}
+	}
+	a := newTestAmalgam(t, &config, []string{"--database-port", "6543"})
+	err := a.LoadMap(map[string]interface{}{
+		"name": "svc",
+		"database": map[string]interface{}{
+			"host":    "db.local",
+			"port":    5432,
+			"timeout": "5s",
+		},
+		"database.replica": map[interface{}]interface{}{"host": "replica.local"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if config.Name != "svc" || config.Database.Host != "db.local" || config.Database.Timeout != 5*time.Second {
+		t.Errorf("got %+v, want the map's values", config)
+	}
+	if config.Database.Port != 6543 {
+		t.Errorf("Database.Port = %d, want the flag to override the map", config.Database.Port)
+	}
+	if config.Database.Replica.Host != "replica.local" {
+		t.Errorf("Database.Replica.Host = %q, want the dotted key's value", config.Database.Replica.Host)
+	}
+}
@@ -0,0 +1,65 @@
+package amalgam
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// versionKey is the config key declaring the version of a config file.
+const versionKey = "version"
+
+// WithConfigVersion sets the current version of the config schema.  Config
+// files declaring an older version (with a top-level `version` key) are
+// upgraded by the migrations registered with WithMigration before they're
+// used.  Files without a version are used as they are.
+func WithConfigVersion(current int) func(*Amalgam) {
+	return func(a *Amalgam) {
+		a.configVersion = current
+	}
+}
+
+// WithMigration registers a function which upgrades the settings of a config
+// file from version `from` to the next version.  The settings have
+// lowercased keys, as with viper.  The function must return a non-nil map,
+// which may be the one it was given.
+func WithMigration(from int, fn func(map[string]interface{}) map[string]interface{}) func(*Amalgam) {
+	return func(a *Amalgam) {
+		if a.migrations == nil {
+			a.migrations = make(map[int]func(map[string]interface{}) map[string]interface{})
+		}
+		a.migrations[from] = fn
+	}
+}
+
+// migrate upgrades the settings of a config file to the current version,
+// and reports whether any migrations were applied.
+func (a *Amalgam) migrate(settings map[string]interface{}) (map[string]interface{}, bool, error) {
+	raw, ok := settings[versionKey]
+	if !ok || a.configVersion == 0 {
+		return settings, false, nil
+	}
+
+	version, err := strconv.Atoi(fmt.Sprint(raw))
+	if err != nil {
+		return nil, false, fmt.Errorf("invalid config version %v", raw)
+	}
+	if version > a.configVersion {
+		return nil, false, fmt.Errorf("config version %d is newer than the supported version %d", version, a.configVersion)
+	}
+	if version == a.configVersion {
+		return settings, false, nil
+	}
+
+	for ; version < a.configVersion; version++ {
+		fn, ok := a.migrations[version]
+		if !ok {
+			return nil, false, fmt.Errorf("no migration from config version %d", version)
+		}
+		if settings = fn(settings); settings == nil {
+			return nil, false, fmt.Errorf("migration from config version %d returned no settings", version)
+		}
+	}
+	settings[versionKey] = a.configVersion
+
+	return settings, true, nil
+}
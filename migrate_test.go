@@ -0,0 +1,60 @@
+package amalgam
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+type migrateConfig struct {
+	Version int
+	Server  struct {
+		Addr string
+	}
+}
+
+// migrateV1 moves the v1 top-level host and port into server.addr.
+func migrateV1(settings map[string]interface{}) map[string]interface{} {
+	settings["server"] = map[string]interface{}{
+		"addr": fmt.Sprintf("%v:%v", settings["host"], settings["port"]),
+	}
+	delete(settings, "host")
+	delete(settings, "port")
+	return settings
+}
+
+func TestMigration(t *testing.T) {
+	var config migrateConfig
+	a := newTestAmalgam(t, &config, nil, WithConfigVersion(2), WithMigration(1, migrateV1))
+	loadYAML(t, a, "version: 1\nhost: localhost\nport: 8080\n")
+
+	if config.Server.Addr != "localhost:8080" || config.Version != 2 {
+		t.Errorf("got %+v, want the v1 file migrated to v2", config)
+	}
+
+	// Current files are used as they are.
+	config = migrateConfig{}
+	a = newTestAmalgam(t, &config, nil, WithConfigVersion(2), WithMigration(1, migrateV1))
+	loadYAML(t, a, "version: 2\nserver:\n  addr: example.com:80\n")
+	if config.Server.Addr != "example.com:80" {
+		t.Errorf("Server.Addr = %q, want the v2 file unchanged", config.Server.Addr)
+	}
+}
+
+func TestMigrationErrors(t *testing.T) {
+	for _, test := range []struct {
+		doc     string
+		options []Option
+		want    string
+	}{
+		{"version: 3\n", nil, "config version 3 is newer than the supported version 2"},
+		{"version: 0\n", nil, "no migration from config version 0"},
+		{"version: one\n", nil, "invalid config version one"},
+		{"version: 1\n", []Option{WithMigration(1, func(map[string]interface{}) map[string]interface{} { return nil })}, "migration from config version 1 returned no settings"},
+	} {
+		var config migrateConfig
+		options := append([]Option{WithConfigVersion(2)}, test.options...)
+		a := newTestAmalgam(t, &config, nil, options...)
+		assertError(t, a.Load(strings.NewReader(test.doc)), test.want)
+	}
+}
@@ -0,0 +1,57 @@
+//go:build go1.18
+// +build go1.18
+
+package amalgam
+
+import (
+	"net/netip"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type netipConfig struct {
+	Addr      netip.Addr
+	Endpoint  netip.AddrPort
+	Network   netip.Prefix
+	Peers     []netip.Addr
+	Endpoints []netip.AddrPort
+	Networks  []netip.Prefix
+}
+
+func TestNetipFields(t *testing.T) {
+	var config netipConfig
+	defer setEnv("NETWORKS", "10.0.0.0/8,fd00::/8")()
+
+	a := newTestAmalgam(t, &config, []string{"--addr", "192.0.2.1", "--peers", "10.0.0.1,::1", "--peers", "10.0.0.2"})
+	loadYAML(t, a, "endpoint: 192.0.2.1:443\nnetwork: 192.0.2.0/24\nendpoints: [\"[::1]:80\", 127.0.0.1:81]\n")
+
+	want := netipConfig{
+		Addr:      netip.MustParseAddr("192.0.2.1"),
+		Endpoint:  netip.MustParseAddrPort("192.0.2.1:443"),
+		Network:   netip.MustParsePrefix("192.0.2.0/24"),
+		Peers:     []netip.Addr{netip.MustParseAddr("10.0.0.1"), netip.MustParseAddr("::1"), netip.MustParseAddr("10.0.0.2")},
+		Endpoints: []netip.AddrPort{netip.MustParseAddrPort("[::1]:80"), netip.MustParseAddrPort("127.0.0.1:81")},
+		Networks:  []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8"), netip.MustParsePrefix("fd00::/8")},
+	}
+	if !reflect.DeepEqual(config, want) {
+		t.Errorf("got %+v, want %+v", config, want)
+	}
+}
+
+func TestInvalidNetipFields(t *testing.T) {
+	for _, test := range []struct {
+		args []string
+		doc  string
+		want string
+	}{
+		{args: []string{"--addr", "300.0.0.1"}, want: "300.0.0.1"},
+		{args: []string{"--peers", "10.0.0.1,bogus"}, want: "bogus"},
+		{doc: "endpoint: 192.0.2.1\n", want: "Endpoint"},
+		{doc: "networks: [10.0.0.0/33]\n", want: "Networks"},
+	} {
+		var config netipConfig
+		a := newTestAmalgam(t, &config, test.args)
+		assertError(t, a.Load(strings.NewReader(test.doc)), test.want)
+	}
+}
@@ -0,0 +1,62 @@
+package amalgam
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/mitchellh/mapstructure"
+)
+
+var numberType = reflect.TypeOf(json.Number(""))
+
+// stringToNumberHookFunc checks that strings decoded into a json.Number are
+// valid numbers.  The value is kept as given, so that large integers don't
+// lose precision.
+func stringToNumberHookFunc() mapstructure.DecodeHookFuncType {
+	return func(f reflect.Type, t reflect.Type, data interface{}) (interface{}, error) {
+		if f.Kind() != reflect.String || t != numberType {
+			return data, nil
+		}
+
+		s := strings.TrimSpace(fmt.Sprint(data))
+		if s == "" {
+			return json.Number(""), nil
+		}
+		if _, err := strconv.ParseFloat(s, 64); err != nil {
+			if numErr, ok := err.(*strconv.NumError); !ok || numErr.Err != strconv.ErrRange {
+				return nil, fmt.Errorf("invalid number %q", s)
+			}
+		}
+		return json.Number(s), nil
+	}
+}
+
+// hasNumberFields reports whether the config object has any json.Number
+// fields.
+func (a *Amalgam) hasNumberFields() bool {
+	for _, info := range a.fields {
+		if info.value.Type() == numberType && !info.excluded() {
+			return true
+		}
+	}
+	return false
+}
+
+// fileNumber returns the value for a json.Number field as written in the
+// config file, rather than as parsed by viper, which converts JSON numbers to
+// float64.  Other settings are returned unchanged.
+func (a *Amalgam) fileNumber(field string, info fieldInfo, raw interface{}) interface{} {
+	if info.value.Type() != numberType || a.rawFileSettings == nil || !a.fromFile(field) {
+		return raw
+	}
+
+	if value, _, ok := lookupRawPath(a.rawFileSettings, strings.Split(field, ".")); ok {
+		if n, ok := value.(json.Number); ok {
+			return n
+		}
+	}
+	return raw
+}
@@ -0,0 +1,42 @@
+package amalgam
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestJSONNumberPrecision(t *testing.T) {
+	const big = "12345678901234567"
+
+	for _, format := range []string{"json", "yaml"} {
+		var config struct {
+			ID json.Number
+		}
+		a := newTestAmalgam(t, &config, nil, WithConfigType(format))
+		doc := "id: " + big + "\n"
+		if format == "json" {
+			doc = `{"id": ` + big + `}`
+		}
+		if err := a.Load(strings.NewReader(doc)); err != nil {
+			t.Fatal(err)
+		}
+		if config.ID != big {
+			t.Errorf("%s: ID = %s, want %s", format, config.ID, big)
+		}
+	}
+}
+
+func TestJSONNumberFlag(t *testing.T) {
+	var config struct {
+		ID json.Number
+	}
+	a := newTestAmalgam(t, &config, []string{"--id", "98765432109876543"})
+	loadYAML(t, a, "id: 1\n")
+	if config.ID != "98765432109876543" {
+		t.Errorf("ID = %s, want the flag value intact", config.ID)
+	}
+
+	a = newTestAmalgam(t, &config, []string{"--id", "12abc"})
+	assertError(t, a.Load(strings.NewReader("")), "12abc")
+}
@@ -0,0 +1,80 @@
+package amalgam
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"strings"
+)
+
+// parsePair checks that s is a key=value pair with a non-empty key.
+func parsePair(s string) error {
+	if idx := strings.Index(s, "="); idx <= 0 {
+		return fmt.Errorf("invalid pair %q, expected key=value", s)
+	}
+	return nil
+}
+
+// pairSliceValue is a pflag.Value for a []string field tagged with the
+// `keyvalue` option.  Each value is a single key=value pair, which may
+// contain commas, and repeating the flag appends to the slice.  It has the
+// methods of pflag.SliceValue.
+type pairSliceValue struct {
+	value   []string
+	changed bool
+}
+
+func newPairSliceValue(val []string) *pairSliceValue {
+	return &pairSliceValue{value: append([]string(nil), val...)}
+}
+
+func (s *pairSliceValue) Set(val string) error {
+	if err := parsePair(val); err != nil {
+		return err
+	}
+
+	if s.changed {
+		s.value = append(s.value, val)
+	} else {
+		s.value = []string{val}
+	}
+	s.changed = true
+
+	return nil
+}
+
+func (s *pairSliceValue) Type() string {
+	return "pairs"
+}
+
+// String renders the pairs as a CSV record in brackets, which is split back
+// into the pairs when decoding.
+func (s *pairSliceValue) String() string {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write(s.value)
+	w.Flush()
+	return "[" + strings.TrimSuffix(buf.String(), "\n") + "]"
+}
+
+func (s *pairSliceValue) Append(val string) error {
+	if err := parsePair(val); err != nil {
+		return err
+	}
+	s.value = append(s.value, val)
+	return nil
+}
+
+func (s *pairSliceValue) Replace(val []string) error {
+	for _, item := range val {
+		if err := parsePair(item); err != nil {
+			return err
+		}
+	}
+	s.value = append([]string(nil), val...)
+	return nil
+}
+
+func (s *pairSliceValue) GetSlice() []string {
+	return append([]string(nil), s.value...)
+}
@@ -0,0 +1,46 @@
+package amalgam
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestKeyValueFlagAppends(t *testing.T) {
+	var config struct {
+		Headers []string `amalgam:",keyvalue"`
+	}
+	a := newTestAmalgam(t, &config, []string{"--headers", "name=X", "--headers", "accept=a,b"})
+	loadYAML(t, a, "headers: [from=file]\n")
+
+	// The flags replace the file's list, rather than appending to it.
+	if want := []string{"name=X", "accept=a,b"}; !reflect.DeepEqual(config.Headers, want) {
+		t.Errorf("Headers = %q, want %q", config.Headers, want)
+	}
+}
+
+func TestKeyValueFlagDefault(t *testing.T) {
+	var config struct {
+		Headers []string `amalgam:",keyvalue"`
+	}
+	config.Headers = []string{"user-agent=amalgam"}
+	a := newTestAmalgam(t, &config, nil)
+	loadYAML(t, a, "")
+	if want := []string{"user-agent=amalgam"}; !reflect.DeepEqual(config.Headers, want) {
+		t.Errorf("Headers = %q, want the default", config.Headers)
+	}
+
+	a = newTestAmalgam(t, &config, []string{"--headers", "x=1"})
+	loadYAML(t, a, "")
+	if want := []string{"x=1"}; !reflect.DeepEqual(config.Headers, want) {
+		t.Errorf("Headers = %q, want the flag to replace the default", config.Headers)
+	}
+}
+
+func TestKeyValueFlagInvalid(t *testing.T) {
+	var config struct {
+		Headers []string `amalgam:",keyvalue"`
+	}
+	a := newTestAmalgam(t, &config, []string{"--headers", "=X"})
+	assertError(t, a.Load(strings.NewReader("")), `invalid pair "=X", expected key=value`)
+}
@@ -0,0 +1,57 @@
+package amalgam
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+var (
+	parsersMu sync.RWMutex
+	parsers   = make(map[reflect.Type]func(string) (interface{}, error))
+)
+
+// RegisterParser registers a function parsing strings into values of the
+// type of example (eg. `semver.Version{}`), so that fields of the type, or
+// pointers to it, are supported from every source, as with types which parse
+// themselves.  The function may return either a value of the type or a
+// pointer to one.  Parsers should be registered before calling New, eg. in an
+// init function.
+func RegisterParser(example interface{}, parse func(string) (interface{}, error)) {
+	t := reflect.TypeOf(example)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	parsersMu.Lock()
+	defer parsersMu.Unlock()
+	parsers[t] = parse
+}
+
+// typeParser returns the parser registered for the type, if any.
+func typeParser(t reflect.Type) func(string) (interface{}, error) {
+	parsersMu.RLock()
+	defer parsersMu.RUnlock()
+	return parsers[t]
+}
+
+// parseRegistered parses a string into a value of the type with its
+// registered parser.
+func parseRegistered(t reflect.Type, parse func(string) (interface{}, error), s string) (reflect.Value, error) {
+	parsed, err := parse(s)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+
+	v := reflect.ValueOf(parsed)
+	if v.Kind() == reflect.Ptr && v.Type().Elem() == t {
+		if v.IsNil() {
+			return reflect.Zero(t), nil
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() || v.Type() != t {
+		return reflect.Value{}, fmt.Errorf("parser for %s returned %T", t, parsed)
+	}
+	return v, nil
+}
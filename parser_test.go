@@ -0,0 +1,52 @@
+package amalgam
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// version stands in for a semver type, registered with RegisterParser.
+type version struct {
+	Major, Minor, Patch int
+}
+
+func (v version) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+func init() {
+	RegisterParser(version{}, func(s string) (interface{}, error) {
+		var v version
+		if _, err := fmt.Sscanf(s, "%d.%d.%d", &v.Major, &v.Minor, &v.Patch); err != nil {
+			return nil, fmt.Errorf("invalid version %q", s)
+		}
+		return &v, nil
+	})
+}
+
+func TestRegisteredParser(t *testing.T) {
+	var config struct {
+		MinVersion version
+		MaxVersion *version
+	}
+	a := newTestAmalgam(t, &config, []string{"--min-version", "1.2.3"})
+	loadYAML(t, a, "maxversion: 2.0.1\n")
+
+	if want := (version{1, 2, 3}); config.MinVersion != want {
+		t.Errorf("MinVersion = %+v, want %+v", config.MinVersion, want)
+	}
+	if want := (version{2, 0, 1}); config.MaxVersion == nil || *config.MaxVersion != want {
+		t.Errorf("MaxVersion = %+v, want %+v", config.MaxVersion, want)
+	}
+}
+
+func TestRegisteredParserError(t *testing.T) {
+	var config struct {
+		MinVersion version
+	}
+	a := newTestAmalgam(t, &config, nil)
+	err := a.Load(strings.NewReader("minversion: latest\n"))
+	assertError(t, err, "MinVersion")
+	assertError(t, err, `invalid version "latest"`)
+}
@@ -0,0 +1,90 @@
+package amalgam
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// maxPort is the highest valid port number.
+const maxPort = 65535
+
+var portRangeType = reflect.TypeOf(PortRange{})
+
+// PortRange is an inclusive range of ports, given as `start-end` (eg.
+// `8000-8100`), or as a single port.  Config fields of this type get a flag,
+// and are parsed the same way from every source.
+type PortRange struct {
+	Start int
+	End   int
+}
+
+// ParsePortRange parses a port range of the form `start-end`, or a single
+// port.
+func ParsePortRange(s string) (PortRange, error) {
+	s = strings.TrimSpace(s)
+	start, end := s, s
+	if idx := strings.Index(s, "-"); idx >= 0 {
+		start, end = s[:idx], s[idx+1:]
+	}
+
+	var r PortRange
+	var err error
+	if r.Start, err = strconv.Atoi(strings.TrimSpace(start)); err != nil {
+		return PortRange{}, fmt.Errorf("invalid port range %q", s)
+	}
+	if r.End, err = strconv.Atoi(strings.TrimSpace(end)); err != nil {
+		return PortRange{}, fmt.Errorf("invalid port range %q", s)
+	}
+	if err := r.check(); err != nil {
+		return PortRange{}, err
+	}
+	return r, nil
+}
+
+// check reports whether the ports are valid, and the start isn't after the
+// end.
+func (r PortRange) check() error {
+	if r.Start < 0 || r.Start > maxPort || r.End < 0 || r.End > maxPort {
+		return fmt.Errorf("invalid port range %s, ports must be between 0 and %d", r, maxPort)
+	}
+	if r.Start > r.End {
+		return fmt.Errorf("invalid port range %s, start is after end", r)
+	}
+	return nil
+}
+
+// Contains reports whether the port is in the range.
+func (r PortRange) Contains(port int) bool {
+	return port >= r.Start && port <= r.End
+}
+
+// String returns the range as `start-end`, or an empty string for the zero
+// range.
+func (r PortRange) String() string {
+	if r == (PortRange{}) {
+		return ""
+	}
+	return fmt.Sprintf("%d-%d", r.Start, r.End)
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (r PortRange) MarshalText() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (r *PortRange) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		*r = PortRange{}
+		return nil
+	}
+
+	parsed, err := ParsePortRange(string(text))
+	if err != nil {
+		return err
+	}
+	*r = parsed
+	return nil
+}
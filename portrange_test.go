@@ -0,0 +1,66 @@
+package amalgam
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParsePortRange(t *testing.T) {
+	for _, test := range []struct {
+		s    string
+		want PortRange
+	}{
+		{"8000-8100", PortRange{8000, 8100}},
+		{" 80 - 81 ", PortRange{80, 81}},
+		{"443", PortRange{443, 443}},
+	} {
+		got, err := ParsePortRange(test.s)
+		if err != nil || got != test.want {
+			t.Errorf("ParsePortRange(%q) = %v, %v, want %v", test.s, got, err, test.want)
+		}
+	}
+
+	for s, want := range map[string]string{
+		"8100-8000": "invalid port range 8100-8000, start is after end",
+		"0-70000":   "invalid port range 0-70000, ports must be between 0 and 65535",
+		"a-b":       `invalid port range "a-b"`,
+	} {
+		_, err := ParsePortRange(s)
+		assertError(t, err, want)
+	}
+}
+
+func TestPortRangeFields(t *testing.T) {
+	var config struct {
+		Ports   PortRange
+		Admin   PortRange
+		Metrics PortRange
+	}
+	defer setEnv("ADMIN", "9000-9001")()
+
+	a := newTestAmalgam(t, &config, []string{"--ports", "8000-8100"})
+	loadYAML(t, a, "metrics: 9100-9200\n")
+
+	if config.Ports != (PortRange{8000, 8100}) || !config.Ports.Contains(8080) {
+		t.Errorf("Ports = %v, want 8000-8100", config.Ports)
+	}
+	if config.Admin != (PortRange{9000, 9001}) {
+		t.Errorf("Admin = %v, want 9000-9001", config.Admin)
+	}
+	if config.Metrics != (PortRange{9100, 9200}) {
+		t.Errorf("Metrics = %v, want 9100-9200", config.Metrics)
+	}
+}
+
+func TestInvertedPortRangeField(t *testing.T) {
+	var config struct {
+		Ports PortRange
+	}
+	a := newTestAmalgam(t, &config, nil)
+	err := a.Load(strings.NewReader("ports: 8100-8000\n"))
+	assertError(t, err, "Ports")
+	assertError(t, err, "start is after end")
+
+	a = newTestAmalgam(t, &config, []string{"--ports", "8100-8000"})
+	assertError(t, a.Load(strings.NewReader("")), "start is after end")
+}
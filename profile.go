@@ -0,0 +1,47 @@
+package amalgam
+
+import (
+	"strings"
+)
+
+// profilesKey is the top-level key holding the profiles in a config file.
+const profilesKey = "profiles"
+
+// WithProfile selects a profile from the top-level `profiles` map of the
+// config file (eg. `dev` or `prod`), merging its settings over the file's
+// other settings.  It's an error if none of the loaded files (including the
+// lack of a config file) has the named profile.
+func WithProfile(name string) func(*Amalgam) {
+	return func(a *Amalgam) {
+		a.profile = name
+	}
+}
+
+// applyProfile merges the selected profile of a config document over its
+// other settings (and raw settings), removing the profiles, and reports
+// whether the document had the profile.
+func (a *Amalgam) applyProfile(settings, raw map[string]interface{}) (map[string]interface{}, map[string]interface{}, bool) {
+	profiles, ok := settings[profilesKey].(map[string]interface{})
+	if a.profile == "" || !ok {
+		return settings, raw, false
+	}
+	profile, found := profiles[strings.ToLower(a.profile)].(map[string]interface{})
+
+	// The raw settings may be the settings themselves (see readDoc), so
+	// look up the raw profile first.
+	if raw != nil {
+		rawProfile, keys, ok := lookupRawPath(raw, []string{profilesKey, a.profile})
+		if m, isMap := rawProfile.(map[string]interface{}); ok && isMap {
+			delete(raw, keys[0])
+			raw = mergeSettings(raw, m)
+		} else if _, keys, ok := lookupRawPath(raw, []string{profilesKey}); ok {
+			delete(raw, keys[0])
+		}
+	}
+
+	delete(settings, profilesKey)
+	if found {
+		settings = mergeSettings(settings, profile)
+	}
+	return settings, raw, found
+}
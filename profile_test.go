@@ -0,0 +1,55 @@
+package amalgam
+
+import (
+	"strings"
+	"testing"
+)
+
+const profileDoc = `
+name: svc
+port: 8080
+database:
+  host: localhost
+  port: 5432
+profiles:
+  dev:
+    debug: true
+  prod:
+    port: 80
+    database:
+      host: db.prod
+`
+
+type profileConfig struct {
+	Name     string
+	Port     int
+	Debug    bool
+	Database struct {
+		Host string
+		Port int
+	}
+}
+
+func TestProfiles(t *testing.T) {
+	var dev profileConfig
+	a := newTestAmalgam(t, &dev, nil, WithProfile("dev"))
+	loadYAML(t, a, profileDoc)
+	if !dev.Debug || dev.Port != 8080 || dev.Database.Host != "localhost" {
+		t.Errorf("dev: got %+v, want the dev profile over the base keys", dev)
+	}
+
+	var prod profileConfig
+	a = newTestAmalgam(t, &prod, nil, WithProfile("prod"))
+	loadYAML(t, a, profileDoc)
+	if prod.Debug || prod.Port != 80 || prod.Database.Host != "db.prod" || prod.Database.Port != 5432 {
+		t.Errorf("prod: got %+v, want the prod profile merged over the base keys", prod)
+	}
+}
+
+func TestUnknownProfile(t *testing.T) {
+	for _, doc := range []string{profileDoc, "name: svc\n", ""} {
+		var config profileConfig
+		a := newTestAmalgam(t, &config, nil, WithProfile("staging"))
+		assertError(t, a.Load(strings.NewReader(doc)), `unknown profile "staging"`)
+	}
+}
@@ -0,0 +1,65 @@
+package amalgam
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+)
+
+// LoadSized is like Load, but with a hint of the size of the config in r,
+// so that it's read into a buffer of that size rather than one grown as it's
+// read (eg. for large configs from a network stream).  The size of files and
+// in-memory readers such as a bytes.Reader is found without a hint.  This
+// only sizes amalgam's own buffer, which is used for formats registered with
+// RegisterFormat and for the raw settings (see WithCaseSensitiveKeys);
+// otherwise the document is streamed into viper, which buffers it itself.
+func (a *Amalgam) LoadSized(r io.Reader, size int64) error {
+	return a.named(a.loadDocs(configDoc{r: r, format: a.formatFor(a.configFile), size: size}))
+}
+
+// readAll reads all of r, preallocating the buffer when the size is known,
+// from the hint or the reader itself.
+func readAll(r io.Reader, size int64) ([]byte, error) {
+	if size <= 0 {
+		size = readerSize(r)
+	}
+	if size <= 0 {
+		return ioutil.ReadAll(r)
+	}
+
+	// The extra space lets the buffer see the end of the reader without
+	// growing.
+	buf := bytes.NewBuffer(make([]byte, 0, size+bytes.MinRead))
+	_, err := buf.ReadFrom(r)
+	return buf.Bytes(), err
+}
+
+// readerSize returns the remaining size of a reader which knows it (eg. a
+// bytes.Reader, or a regular file), or 0.
+func readerSize(r io.Reader) int64 {
+	switch r := r.(type) {
+	case interface{ Len() int }:
+		return int64(r.Len())
+	case *os.File:
+		if fi, err := r.Stat(); err == nil && fi.Mode().IsRegular() {
+			return fi.Size()
+		}
+	}
+	return 0
+}
+
+// errReader records the first error reading from r, other than io.EOF, as
+// viper ignores errors reading a config document.
+type errReader struct {
+	r   io.Reader
+	err error
+}
+
+func (e *errReader) Read(p []byte) (int, error) {
+	n, err := e.r.Read(p)
+	if err != nil && err != io.EOF && e.err == nil {
+		e.err = err
+	}
+	return n, err
+}
@@ -0,0 +1,112 @@
+package amalgam
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/spf13/pflag"
+)
+
+// largeConfig returns a YAML document with a map of n entries.
+func largeConfig(n int) []byte {
+	var b bytes.Buffer
+	b.WriteString("hosts:\n")
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&b, "  host%d: 10.0.%d.%d\n", i, i/256%256, i%256)
+	}
+	return b.Bytes()
+}
+
+func benchmarkLoad(b *testing.B, load func(a *Amalgam, r io.Reader, size int64) error) {
+	data := largeConfig(10000)
+	b.ReportAllocs()
+	b.SetBytes(int64(len(data)))
+	for i := 0; i < b.N; i++ {
+		var config struct {
+			Hosts map[string]string
+		}
+		fs := pflag.NewFlagSet("bench", pflag.ContinueOnError)
+		a, err := New(&config, WithFlagSet(fs), WithArgs([]string{}), WithConfigType("yaml"))
+		if err != nil {
+			b.Fatal(err)
+		}
+		// Hide the reader's size, as for a network stream.
+		r := struct{ io.Reader }{bytes.NewReader(data)}
+		if err := load(a, r, int64(len(data))); err != nil {
+			b.Fatal(err)
+		}
+		if len(config.Hosts) != 10000 {
+			b.Fatalf("loaded %d hosts", len(config.Hosts))
+		}
+	}
+}
+
+func BenchmarkLoadUnsized(b *testing.B) {
+	benchmarkLoad(b, func(a *Amalgam, r io.Reader, size int64) error {
+		return a.Load(r)
+	})
+}
+
+func BenchmarkLoadSized(b *testing.B) {
+	benchmarkLoad(b, func(a *Amalgam, r io.Reader, size int64) error {
+		return a.LoadSized(r, size)
+	})
+}
+
+func TestReadAllSizes(t *testing.T) {
+	data := largeConfig(100)
+	for _, tc := range []struct {
+		name string
+		r    io.Reader
+		size int64
+	}{
+		{"known size", bytes.NewReader(data), 0},
+		{"hint", struct{ io.Reader }{bytes.NewReader(data)}, int64(len(data))},
+		{"wrong hint", struct{ io.Reader }{bytes.NewReader(data)}, 10},
+		{"no size", struct{ io.Reader }{bytes.NewReader(data)}, 0},
+	} {
+		got, err := readAll(tc.r, tc.size)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if !bytes.Equal(got, data) {
+			t.Errorf("%s: read %d bytes, want %d", tc.name, len(got), len(data))
+		}
+	}
+}
+
+// failingReader returns the data from r, then an error instead of io.EOF.
+type failingReader struct {
+	r io.Reader
+}
+
+func (f failingReader) Read(p []byte) (int, error) {
+	n, err := f.r.Read(p)
+	if err == io.EOF {
+		err = errors.New("connection reset")
+	}
+	return n, err
+}
+
+func TestLoadStreamed(t *testing.T) {
+	var config struct {
+		Name string
+	}
+	a := newTestAmalgam(t, &config, nil)
+
+	// Compressed documents are detected in a stream too.
+	if err := a.Load(strings.NewReader(gzipData(t, "name: compressed\n"))); err != nil {
+		t.Fatal(err)
+	}
+	if config.Name != "compressed" {
+		t.Errorf("Name = %q, want compressed", config.Name)
+	}
+
+	// viper ignores read errors, so they're checked for separately.
+	assertError(t, a.Load(failingReader{strings.NewReader("name: svc\n")}), "connection reset")
+	assertError(t, a.Load(failingReader{strings.NewReader(gzipData(t, "name: svc\n"))}), "invalid gzip-compressed config: connection reset")
+}
@@ -0,0 +1,83 @@
+package amalgam
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// redacted replaces the values of secret fields in RedactedString.
+const redacted = "[REDACTED]"
+
+// RedactedString renders the config object like the `%+v` verb, but with the
+// values of fields tagged with the `secret` option (or the whole of a nested
+// struct so tagged) replaced by `[REDACTED]`, eg. for logging the config.
+func (a *Amalgam) RedactedString() string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	return redactValue(reflect.ValueOf(a.configObj))
+}
+
+// redactValue renders a value, redacting the secret fields of structs,
+// including those in slices and maps.
+func redactValue(v reflect.Value) string {
+	v = indirect(v)
+	if !v.IsValid() {
+		return "<nil>"
+	}
+
+	t := v.Type()
+	if t == timeType || isValueType(t) || !containsStruct(t) {
+		return fmt.Sprintf("%+v", v)
+	}
+
+	var b strings.Builder
+	switch t.Kind() {
+	case reflect.Struct:
+		b.WriteString("{")
+		for i := 0; i < t.NumField(); i++ {
+			if i > 0 {
+				b.WriteString(" ")
+			}
+			field := t.Field(i)
+			b.WriteString(field.Name + ":")
+			if _, options, _ := parseTag(field.Tag.Get(tagName)); options.has("secret") {
+				b.WriteString(redacted)
+			} else {
+				b.WriteString(redactValue(v.Field(i)))
+			}
+		}
+		b.WriteString("}")
+	case reflect.Slice, reflect.Array:
+		b.WriteString("[")
+		for i := 0; i < v.Len(); i++ {
+			if i > 0 {
+				b.WriteString(" ")
+			}
+			b.WriteString(redactValue(v.Index(i)))
+		}
+		b.WriteString("]")
+	case reflect.Map:
+		items := make([]string, 0, v.Len())
+		for _, key := range v.MapKeys() {
+			items = append(items, fmt.Sprintf("%+v:%s", key, redactValue(v.MapIndex(key))))
+		}
+		sort.Strings(items)
+		b.WriteString("map[" + strings.Join(items, " ") + "]")
+	}
+	return b.String()
+}
+
+// containsStruct reports whether values of the type may hold struct fields,
+// ie. it's a struct, or a pointer, slice, array or map of them.
+func containsStruct(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Struct:
+		return true
+	case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map:
+		return containsStruct(t.Elem())
+	}
+	return false
+}
@@ -0,0 +1,38 @@
+package amalgam
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRedactedString(t *testing.T) {
+	type credentials struct {
+		User     string
+		Password string
+	}
+	type user struct {
+		Name string
+		Key  string `amalgam:"-,secret"`
+	}
+	var config struct {
+		Name     string
+		Token    string      `amalgam:",secret"`
+		Database credentials `amalgam:",secret"`
+		Users    []user
+	}
+	a := newTestAmalgam(t, &config, nil)
+	loadYAML(t, a, "name: svc\ntoken: hunter2\ndatabase:\n  user: admin\n  password: s3cret\n")
+	config.Users = append(config.Users, user{"alice", "k3y"})
+
+	got := a.RedactedString()
+	for _, secret := range []string{"hunter2", "s3cret", "admin", "k3y"} {
+		if strings.Contains(got, secret) {
+			t.Errorf("RedactedString() = %s, which leaks %q", got, secret)
+		}
+	}
+	for _, want := range []string{"Name:svc", "Token:[REDACTED]", "Database:[REDACTED]", "Name:alice", "Key:[REDACTED]"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("RedactedString() = %s, want it to contain %s", got, want)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package amalgam
+
+import (
+	"errors"
+	"reflect"
+)
+
+// Reload re-reads the config file previously loaded by LoadFile, and
+// re-populates the config object, eg. on SIGHUP.  The command-line flags
+// aren't parsed again.  Slices and maps in the config object are replaced
+// rather than merged into, so entries removed from the file are removed.
+//
+// The config object is updated with the write lock held, so code reading it
+// concurrently should hold the read lock (see RLock).
+func (a *Amalgam) Reload() error {
+	return a.named(a.reload(false))
+}
+
+// LoadChanged is like Reload, but only updates the fields whose resolved
+// values have changed since the last load, leaving the others untouched (eg.
+// where other code has modified them in the meantime).
+func (a *Amalgam) LoadChanged() error {
+	return a.named(a.reload(true))
+}
+
+// reload does the work of Reload and LoadChanged.
+func (a *Amalgam) reload(changedOnly bool) error {
+	if a.loadedFile == "" {
+		return errors.New("no config file has been loaded")
+	}
+
+	var docs []configDoc
+	var closeFiles func()
+	var err error
+	if a.loadedLayered {
+		docs, closeFiles, err = a.openConfigFiles(a.configPath(a.baseFile), a.layeredFiles()...)
+	} else {
+		docs, closeFiles, err = a.openConfigFiles(a.loadedFile)
+	}
+	if err != nil {
+		return err
+	}
+	defer closeFiles()
+
+	a.mu.Lock()
+	a.reloading, a.changedOnly = true, changedOnly
+	err = a.loadLocked(docs...)
+	a.reloading, a.changedOnly = false, false
+	a.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	a.notifyLoad()
+	return nil
+}
+
+// OnLoad registers a function to be called after the config has been
+// successfully loaded or reloaded.
+func (a *Amalgam) OnLoad(fn func()) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.onLoad = append(a.onLoad, fn)
+}
+
+// OnKeyChange registers a function to be called when a reload changes the
+// resolved value of the key (the dotted field path, eg. `API.Endpoint`,
+// matched case-insensitively), with its old and new values.  Reloads which
+// leave the key unchanged don't call it.
+func (a *Amalgam) OnKeyChange(key string, fn func(old, new interface{})) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.onKeyChange = append(a.onKeyChange, keyChangeFunc{key, fn})
+}
+
+// keyChangeFunc is a function registered with OnKeyChange.
+type keyChangeFunc struct {
+	key string
+	fn  func(old, new interface{})
+}
+
+// RLock takes the read lock, which prevents the config object being updated
+// by Reload (or any other load) until RUnlock is called.
+func (a *Amalgam) RLock() {
+	a.mu.RLock()
+}
+
+// RUnlock releases the read lock taken by RLock.
+func (a *Amalgam) RUnlock() {
+	a.mu.RUnlock()
+}
+
+// notifyLoad calls the functions registered with OnLoad, and those
+// registered with OnKeyChange whose key has changed since the previous load.
+func (a *Amalgam) notifyLoad() {
+	settings := a.Settings()
+
+	a.mu.Lock()
+	callbacks, keyCallbacks := a.onLoad, a.onKeyChange
+	prev := a.loadedSettings
+	a.loadedSettings = settings
+	a.mu.Unlock()
+
+	for _, fn := range callbacks {
+		fn()
+	}
+	if prev == nil {
+		return
+	}
+	for _, kc := range keyCallbacks {
+		field, _, ok := a.lookupField(kc.key)
+		if !ok {
+			continue
+		}
+		if old, value := prev[field], settings[field]; !reflect.DeepEqual(old, value) {
+			kc.fn(old, value)
+		}
+	}
+}
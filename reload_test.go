@@ -0,0 +1,125 @@
+package amalgam
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestLoadChanged(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+	path := writeFile(t, dir, "config.yaml", "name: svc\nport: 8080\n")
+
+	var config struct {
+		Name string
+		Port int
+	}
+	a := newTestAmalgam(t, &config, []string{"--config", path}, WithConfigType(""))
+	if err := a.LoadFile(); err != nil {
+		t.Fatal(err)
+	}
+
+	config.Name = "mutated"
+	writeFile(t, dir, "config.yaml", "name: svc\nport: 9090\n")
+	if err := a.LoadChanged(); err != nil {
+		t.Fatal(err)
+	}
+
+	if config.Port != 9090 {
+		t.Errorf("Port = %d, want the changed value", config.Port)
+	}
+	if config.Name != "mutated" {
+		t.Errorf("Name = %q, want the mutated value left untouched", config.Name)
+	}
+
+	if err := a.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if config.Name != "svc" {
+		t.Errorf("Name = %q, want Reload to reset every field", config.Name)
+	}
+}
+
+func TestReloadWithoutFile(t *testing.T) {
+	var config struct {
+		Name string
+	}
+	a := newTestAmalgam(t, &config, nil)
+	loadYAML(t, a, "name: svc\n")
+	assertError(t, a.LoadChanged(), "no config file has been loaded")
+}
+
+func TestOnKeyChange(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+	path := writeFile(t, dir, "config.yaml", "name: svc\napi:\n  endpoint: http://a\n")
+
+	var config struct {
+		Name string
+		API  struct {
+			Endpoint string
+		}
+	}
+	a := newTestAmalgam(t, &config, []string{"--config", path}, WithConfigType(""))
+
+	var changes []string
+	a.OnKeyChange("API.Endpoint", func(old, new interface{}) {
+		changes = append(changes, fmt.Sprintf("endpoint %v -> %v", old, new))
+	})
+	a.OnKeyChange("name", func(old, new interface{}) {
+		changes = append(changes, fmt.Sprintf("name %v -> %v", old, new))
+	})
+	if err := a.LoadFile(); err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 0 {
+		t.Errorf("got changes %q on the first load, want none", changes)
+	}
+
+	writeFile(t, dir, "config.yaml", "name: svc\napi:\n  endpoint: http://b\n")
+	if err := a.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"endpoint http://a -> http://b"}; !reflect.DeepEqual(changes, want) {
+		t.Errorf("got changes %q, want %q", changes, want)
+	}
+
+	changes = nil
+	if err := a.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 0 {
+		t.Errorf("got changes %q from an unchanged reload, want none", changes)
+	}
+}
+
+func TestOnLoad(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+	path := writeFile(t, dir, "config.yaml", "name: svc\n")
+
+	var config struct {
+		Name string
+	}
+	a := newTestAmalgam(t, &config, []string{"--config", path})
+	var loaded []string
+	a.OnLoad(func() { loaded = append(loaded, config.Name) })
+
+	if err := a.LoadFile(); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, dir, "config.yaml", "name: renamed\n")
+	if err := a.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, dir, "config.yaml", "name: [bad\n")
+	if err := a.Reload(); err == nil {
+		t.Fatal("Reload of an invalid file succeeded")
+	}
+
+	// The callback sees the loaded config, and isn't called on failure.
+	if want := []string{"svc", "renamed"}; !reflect.DeepEqual(loaded, want) {
+		t.Errorf("OnLoad called with %q, want %q", loaded, want)
+	}
+}
@@ -0,0 +1,72 @@
+package amalgam
+
+import (
+	"reflect"
+	"strings"
+)
+
+var remainType = reflect.TypeOf(map[string]interface{}(nil))
+
+// remainField returns the field tagged with `remain` in the struct at the
+// field path prefix (the top-level struct for ""), if any.
+func (a *Amalgam) remainField(prefix string) string {
+	for field, info := range a.fields {
+		if info.excluded() || !info.options.has("remain") {
+			continue
+		}
+		parent := ""
+		if idx := strings.LastIndex(field, "."); idx >= 0 {
+			parent = field[:idx]
+		}
+		if strings.EqualFold(parent, prefix) {
+			return field
+		}
+	}
+	return ""
+}
+
+// collectRemain sets each field tagged with `remain` to the keys in its
+// struct in the config file which don't match a field, along with any
+// settings given for the field itself.  The field is left untouched if it's
+// in unchanged.
+func (a *Amalgam) collectRemain(unchanged map[string]bool) {
+	for field, info := range a.fields {
+		if info.excluded() || unchanged[field] || !info.options.has("remain") {
+			continue
+		}
+		v := a.fieldValue(info)
+		if !v.IsValid() || !v.CanSet() || v.Type() != remainType {
+			continue
+		}
+
+		remain := make(map[string]interface{})
+		if own, ok := lookupPath(a.fileSettings, keyPath(field)); ok {
+			if m, ok := own.(map[string]interface{}); ok {
+				for key, value := range m {
+					remain[key] = value
+				}
+			}
+		}
+
+		settings, prefix := a.fileSettings, ""
+		if idx := strings.LastIndex(field, "."); idx >= 0 {
+			prefix = field[:idx]
+			parent, _ := lookupPath(a.fileSettings, keyPath(prefix))
+			settings, _ = parent.(map[string]interface{})
+		}
+		for key, value := range settings {
+			path := key
+			if prefix != "" {
+				path = prefix + "." + key
+			} else if key == versionKey && a.configVersion != 0 {
+				continue
+			}
+			if _, _, ok := a.lookupField(path); ok || a.hasFieldsUnder(path) {
+				continue
+			}
+			remain[key] = value
+		}
+
+		v.Set(reflect.ValueOf(remain))
+	}
+}
@@ -0,0 +1,36 @@
+package amalgam
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRemain(t *testing.T) {
+	var config struct {
+		Name   string
+		Extra  map[string]interface{} `amalgam:",remain"`
+		Plugin struct {
+			Enabled bool
+			Options map[string]interface{} `amalgam:",remain"`
+		}
+	}
+	a := newTestAmalgam(t, &config, nil, WithStrictKeys())
+	loadYAML(t, a, `
+name: svc
+color: blue
+retries: 3
+plugin:
+  enabled: true
+  endpoint: http://plugin
+`)
+
+	if want := map[string]interface{}{"color": "blue", "retries": 3}; !reflect.DeepEqual(config.Extra, want) {
+		t.Errorf("Extra = %#v, want %#v", config.Extra, want)
+	}
+	if want := map[string]interface{}{"endpoint": "http://plugin"}; !reflect.DeepEqual(config.Plugin.Options, want) {
+		t.Errorf("Plugin.Options = %#v, want %#v", config.Plugin.Options, want)
+	}
+	if config.Name != "svc" || !config.Plugin.Enabled {
+		t.Errorf("got %+v, want the matched keys decoded as usual", config)
+	}
+}
@@ -0,0 +1,49 @@
+package amalgam
+
+import "strings"
+
+// WithKeyRewriter rewrites the keys in config files before they're matched
+// to fields, eg. to map snake_case keys (`max_idle_conns`) to the field
+// names (`MaxIdleConns`) where the struct can't be tagged.  The function is
+// given each key in lower case, as viper reads it, and keys are matched to
+// the rewritten names case-insensitively.  The keys of map fields aren't
+// rewritten.
+func WithKeyRewriter(fn func(fileKey string) string) func(*Amalgam) {
+	return func(a *Amalgam) {
+		a.keyRewriter = fn
+	}
+}
+
+// rewriteKeys returns a copy of the settings for the field path prefix with
+// the keys rewritten, descending into nested structs and lists, but not map
+// fields.
+func (a *Amalgam) rewriteKeys(settings map[string]interface{}, prefix string) map[string]interface{} {
+	rewritten := make(map[string]interface{}, len(settings))
+	for key, value := range settings {
+		key = a.keyRewriter(strings.ToLower(key))
+		field := key
+		if prefix != "" {
+			field = prefix + "." + key
+		}
+
+		switch v := value.(type) {
+		case map[string]interface{}:
+			if _, _, isField := a.lookupField(field); !isField {
+				value = a.rewriteKeys(v, field)
+			}
+		case []interface{}:
+			// Items of slices of structs aren't fields themselves, so
+			// their keys are all rewritten.
+			items := make([]interface{}, len(v))
+			for i, item := range v {
+				if m, ok := settingsMap(item); ok {
+					item = a.rewriteKeys(m, field+".[]")
+				}
+				items[i] = item
+			}
+			value = items
+		}
+		rewritten[key] = value
+	}
+	return rewritten
+}
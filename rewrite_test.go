@@ -0,0 +1,31 @@
+package amalgam
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestKeyRewriter(t *testing.T) {
+	var config struct {
+		MaxIdleConns int
+		Database     struct {
+			ConnTimeout string
+		}
+		Labels map[string]string
+	}
+	snakeToCamel := func(key string) string {
+		return strings.Replace(key, "_", "", -1)
+	}
+	a := newTestAmalgam(t, &config, nil, WithKeyRewriter(snakeToCamel))
+	loadYAML(t, a, "max_idle_conns: 5\ndatabase:\n  conn_timeout: 5s\nlabels:\n  team_name: core\n")
+
+	if config.MaxIdleConns != 5 {
+		t.Errorf("MaxIdleConns = %d, want 5", config.MaxIdleConns)
+	}
+	if config.Database.ConnTimeout != "5s" {
+		t.Errorf("Database.ConnTimeout = %q, want 5s", config.Database.ConnTimeout)
+	}
+	if config.Labels["team_name"] != "core" {
+		t.Errorf("Labels = %v, want the map keys left as they are", config.Labels)
+	}
+}
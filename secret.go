@@ -0,0 +1,98 @@
+package amalgam
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// WithSecretFilePermissionCheck checks that config files providing the
+// values of secret fields (tagged with the `secret` option) aren't readable
+// or writable by the group or others, ie. have permissions of 0600 or
+// stricter.  Loading fails if they are.  Config which isn't loaded from a
+// file on disk (eg. by Load or LoadURL) isn't checked.
+func WithSecretFilePermissionCheck() func(*Amalgam) {
+	return func(a *Amalgam) {
+		a.secretPermCheck = true
+	}
+}
+
+// WithSecretsDir reads the values of secret fields (tagged with the `secret`
+// option) from files in a directory, as mounted by Docker and Kubernetes
+// secrets.  Each field is read from the file named after its flag (eg.
+// `/run/secrets/db-password` for `DB.Password`), with its contents trimmed of
+// surrounding whitespace.  These take precedence over the environment and the
+// config file, but not over flags.  Missing files are skipped.
+func WithSecretsDir(dir string) func(*Amalgam) {
+	return func(a *Amalgam) {
+		a.secretsDir = dir
+	}
+}
+
+// readSecretsDir reads the values of the secret fields from the secrets
+// directory into values, skipping fields given by a flag or --set, and
+// everything when loading only the flags.
+func (a *Amalgam) readSecretsDir(values map[string]string) error {
+	if a.secretsDir == "" || a.flagsOnly {
+		return nil
+	}
+
+	for field, info := range a.fields {
+		if info.excluded() || !info.options.has("secret") || a.flagOrSetValue(field) {
+			continue
+		}
+
+		data, err := ioutil.ReadFile(filepath.Join(a.secretsDir, info.flagName))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %v", field, err)
+		}
+		values[field] = strings.TrimSpace(string(data))
+	}
+	return nil
+}
+
+// recordSecretSources records the named file as the source of each secret
+// field present in its settings, replacing any earlier file.
+func (a *Amalgam) recordSecretSources(sources map[string]string, name string, settings map[string]interface{}) {
+	if !a.secretPermCheck {
+		return
+	}
+	for field, info := range a.fields {
+		if info.excluded() || !info.options.has("secret") {
+			continue
+		}
+		if _, ok := lookupPath(settings, keyPath(field)); ok {
+			sources[field] = name
+		}
+	}
+}
+
+// checkSecretFiles checks the permissions of the files providing the values
+// of secret fields, for those values which aren't overridden.
+func (a *Amalgam) checkSecretFiles() error {
+	var errs ErrorList
+	checked := make(map[string]bool)
+	for _, field := range a.sortedFields() {
+		name, ok := a.secretSources[field]
+		if !ok || checked[name] || !a.fromFile(field) {
+			continue
+		}
+		checked[name] = true
+
+		fi, err := os.Stat(name)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if perm := fi.Mode().Perm(); perm&0077 != 0 {
+			errs = append(errs, fmt.Errorf("config file %s with secret %s is accessible by others (mode %#o, should be 0600 or stricter)", name, field, perm))
+		}
+	}
+
+	return errs.err()
+}
@@ -0,0 +1,113 @@
+package amalgam
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+type secretConfig struct {
+	Name     string
+	Password string `amalgam:",secret"`
+}
+
+func TestSecretFilePermissions(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+	path := writeFile(t, dir, "config.yaml", "name: svc\npassword: hunter2\n")
+
+	for _, test := range []struct {
+		mode os.FileMode
+		ok   bool
+	}{
+		{0600, true},
+		{0400, true},
+		{0640, false},
+		{0644, false},
+	} {
+		if err := os.Chmod(path, test.mode); err != nil {
+			t.Fatal(err)
+		}
+		var config secretConfig
+		a := newTestAmalgam(t, &config, []string{"--config", path}, WithConfigType(""), WithSecretFilePermissionCheck())
+		err := a.LoadFile()
+		if test.ok && err != nil {
+			t.Errorf("mode %#o: %v", test.mode, err)
+		}
+		if !test.ok {
+			assertError(t, err, "with secret Password is accessible by others")
+		}
+	}
+}
+
+func TestSecretFilePermissionsOverridden(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+	path := writeFile(t, dir, "config.yaml", "name: svc\npassword: hunter2\n")
+	if err := os.Chmod(path, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The secret from the file isn't used, so its permissions don't matter.
+	var config secretConfig
+	a := newTestAmalgam(t, &config, []string{"--config", path, "--password", "flag"}, WithConfigType(""), WithSecretFilePermissionCheck())
+	if err := a.LoadFile(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Nor do the permissions of files without secrets.
+	path = writeFile(t, dir, "public.yaml", "name: svc\n")
+	if err := os.Chmod(path, 0644); err != nil {
+		t.Fatal(err)
+	}
+	a = newTestAmalgam(t, &config, []string{"--config", path}, WithConfigType(""), WithSecretFilePermissionCheck())
+	if err := a.LoadFile(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Nor the permissions of a reader.
+	a = newTestAmalgam(t, &config, nil, WithSecretFilePermissionCheck())
+	if err := a.Load(strings.NewReader("password: hunter2\n")); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSecretsDir(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+	writeFile(t, dir, "password", "  hunter2\n")
+	writeFile(t, dir, "db-password", "s3cret\n")
+	writeFile(t, dir, "name", "not-a-secret\n")
+
+	var config struct {
+		Name     string
+		Password string `amalgam:",secret"`
+		Token    string `amalgam:",secret"`
+		DB       struct {
+			Password string `amalgam:",secret"`
+		}
+	}
+	defer setEnv("PASSWORD", "from-env")()
+
+	a := newTestAmalgam(t, &config, nil, WithSecretsDir(dir))
+	loadYAML(t, a, "name: svc\ntoken: from-file\ndb:\n  password: from-file\n")
+
+	if config.Password != "hunter2" {
+		t.Errorf("Password = %q, want the trimmed secret file over the env", config.Password)
+	}
+	if config.DB.Password != "s3cret" {
+		t.Errorf("DB.Password = %q, want the secret file over the config file", config.DB.Password)
+	}
+	if config.Token != "from-file" {
+		t.Errorf("Token = %q, want the config file's value without a secret file", config.Token)
+	}
+	if config.Name != "svc" {
+		t.Errorf("Name = %q, want non-secret fields not read from the directory", config.Name)
+	}
+
+	a = newTestAmalgam(t, &config, []string{"--password", "from-flag"}, WithSecretsDir(dir))
+	loadYAML(t, a, "")
+	if config.Password != "from-flag" {
+		t.Errorf("Password = %q, want the flag over the secret file", config.Password)
+	}
+}
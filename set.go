@@ -0,0 +1,308 @@
+package amalgam
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/pelletier/go-toml"
+	"gopkg.in/yaml.v2"
+)
+
+// WithAutoPersist writes the loaded config file after each successful call
+// to Set.
+func WithAutoPersist() func(*Amalgam) {
+	return func(a *Amalgam) {
+		a.autoPersist = true
+	}
+}
+
+// Set updates the value of a config key, in both the config object and the
+// underlying viper instance.  The key is the dotted field path (eg.
+// `API.Endpoint`), matched case-insensitively.  The value must be assignable
+// to the field, or be a number which a numeric field can hold exactly.
+//
+// Values set this way take precedence over all other sources, including
+// when the config is reloaded.  If WithAutoPersist was specified, the config
+// file is written afterwards.
+func (a *Amalgam) Set(key string, value interface{}) error {
+	return a.named(a.set(key, value))
+}
+
+// set does the work of Set.
+func (a *Amalgam) set(key string, value interface{}) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.frozen {
+		return ErrFrozen
+	}
+
+	field, info, ok := a.lookupField(key)
+	if !ok {
+		return fmt.Errorf("unknown config key %q", key)
+	}
+	target := a.fieldValue(info)
+	if info.excluded() || !target.CanSet() {
+		return fmt.Errorf("config key %q cannot be set", field)
+	}
+
+	v := reflect.ValueOf(value)
+	t := info.value.Type()
+	if !v.IsValid() {
+		return fmt.Errorf("cannot set config key %q to nil", field)
+	}
+	if !v.Type().AssignableTo(t) {
+		if !isNumericKind(v.Kind()) || !isNumericKind(t.Kind()) {
+			return fmt.Errorf("cannot set config key %q of type %s to a value of type %s", field, t, v.Type())
+		}
+		converted, ok := convertNumber(v, t)
+		if !ok {
+			return fmt.Errorf("cannot set config key %q of type %s to %v", field, t, value)
+		}
+		v = converted
+	}
+
+	target.Set(v)
+	a.viper.Set(field, v.Interface())
+	if a.setFields == nil {
+		a.setFields = make(map[string]bool)
+	}
+	a.setFields[field] = true
+
+	if a.autoPersist {
+		return a.persist()
+	}
+
+	return nil
+}
+
+// Persist writes the values given to Set to the config file loaded by
+// LoadFile (the override file, for layered files), along with the settings
+// already in the file, which are kept as they're written.  Values from other
+// sources, such as the environment, flags and secrets, aren't written.  Only
+// YAML, JSON and TOML files can be written, and not compressed ones.
+func (a *Amalgam) Persist() error {
+	return a.named(a.persist())
+}
+
+// persist does the work of Persist.
+func (a *Amalgam) persist() error {
+	if a.loadedFile == "" {
+		return errors.New("no config file has been loaded")
+	}
+	format := strings.ToLower(a.formatFor(a.loadedFile))
+	if strings.HasSuffix(a.loadedFile, gzipExt) {
+		return fmt.Errorf("cannot persist to %s: writing compressed config isn't supported", a.loadedFile)
+	}
+	if !isWritableFormat(format) {
+		return fmt.Errorf("cannot persist to %s: writing %q config isn't supported", a.loadedFile, format)
+	}
+
+	settings, err := a.persistedSettings(format)
+	if err != nil {
+		return err
+	}
+	data, err := marshalRawConfig(format, settings)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(a.loadedFile, data, 0644)
+}
+
+// persistedSettings returns the settings of the loaded config file, as it is
+// now, updated with the values given to Set.  The file is parsed as it's
+// written, without lowercasing or rewriting its keys, so that only the keys
+// given to Set change.  A missing file has no settings.
+func (a *Amalgam) persistedSettings(format string) (map[string]interface{}, error) {
+	settings := make(map[string]interface{})
+	data, err := ioutil.ReadFile(a.loadedFile)
+	switch {
+	case err == nil:
+		if settings, err = parseRawConfig(format, data); err != nil {
+			return nil, err
+		}
+	case !os.IsNotExist(err):
+		return nil, err
+	}
+
+	for field := range a.setFields {
+		setPath(settings, a.filePath(settings, field), a.viper.Get(field))
+	}
+	return settings, nil
+}
+
+// filePath returns the path of keys for the field in the raw settings of a
+// config file.  Keys already in the file are kept as they're written there,
+// including keys which WithKeyRewriter rewrites to the field names, while
+// missing keys are added in lower case.
+func (a *Amalgam) filePath(settings map[string]interface{}, field string) []string {
+	names := strings.Split(field, ".")
+	path := make([]string, len(names))
+	for i, name := range names {
+		key, ok := a.fileKey(settings, name)
+		if !ok {
+			key = strings.ToLower(name)
+		}
+		path[i] = key
+		settings, _ = settings[key].(map[string]interface{})
+	}
+	return path
+}
+
+// fileKey finds the key in the raw settings matching a field name, preferring
+// an exact match.
+func (a *Amalgam) fileKey(settings map[string]interface{}, name string) (string, bool) {
+	if _, ok := settings[name]; ok {
+		return name, true
+	}
+	for key := range settings {
+		if strings.EqualFold(key, name) {
+			return key, true
+		}
+		if a.keyRewriter != nil && strings.EqualFold(a.keyRewriter(strings.ToLower(key)), name) {
+			return key, true
+		}
+	}
+	return "", false
+}
+
+// isWritableFormat reports whether Persist can write config in the format.
+func isWritableFormat(format string) bool {
+	switch format {
+	case "yaml", "yml", "json", "toml":
+		return true
+	}
+	return false
+}
+
+// marshalRawConfig encodes settings in the format, as parsed by
+// parseRawConfig.
+func marshalRawConfig(format string, settings map[string]interface{}) ([]byte, error) {
+	switch format {
+	case "json":
+		data, err := json.MarshalIndent(settings, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(data, '\n'), nil
+	case "toml":
+		tree, err := toml.TreeFromMap(settings)
+		if err != nil {
+			return nil, err
+		}
+		s, err := tree.ToTomlString()
+		return []byte(s), err
+	}
+	return yaml.Marshal(settings)
+}
+
+// lookupField finds the field for a dotted key, ignoring case as viper does.
+func (a *Amalgam) lookupField(key string) (string, fieldInfo, bool) {
+	if info, ok := a.fields[key]; ok {
+		return key, info, true
+	}
+	for field, info := range a.fields {
+		if strings.EqualFold(field, key) {
+			return field, info, true
+		}
+	}
+
+	return "", fieldInfo{}, false
+}
+
+// convertNumber converts a numeric value to the numeric type t, reporting
+// whether t holds the value exactly: integers must be in range, and floats
+// converted to integers must be whole numbers.
+func convertNumber(v reflect.Value, t reflect.Type) (reflect.Value, bool) {
+	target := reflect.New(t).Elem()
+	switch {
+	case isSignedKind(v.Kind()):
+		n := v.Int()
+		switch {
+		case isSignedKind(t.Kind()):
+			if target.OverflowInt(n) {
+				return reflect.Value{}, false
+			}
+		case isUintKind(t.Kind()):
+			if n < 0 || target.OverflowUint(uint64(n)) {
+				return reflect.Value{}, false
+			}
+		}
+	case isUintKind(v.Kind()):
+		n := v.Uint()
+		switch {
+		case isSignedKind(t.Kind()):
+			if n > math.MaxInt64 || target.OverflowInt(int64(n)) {
+				return reflect.Value{}, false
+			}
+		case isUintKind(t.Kind()):
+			if target.OverflowUint(n) {
+				return reflect.Value{}, false
+			}
+		}
+	default:
+		f := v.Float()
+		switch {
+		case isSignedKind(t.Kind()):
+			if f != math.Trunc(f) || f < math.MinInt64 || f >= math.MaxInt64 || target.OverflowInt(int64(f)) {
+				return reflect.Value{}, false
+			}
+		case isUintKind(t.Kind()):
+			if f != math.Trunc(f) || f < 0 || f >= math.MaxUint64 || target.OverflowUint(uint64(f)) {
+				return reflect.Value{}, false
+			}
+		default:
+			if target.OverflowFloat(f) {
+				return reflect.Value{}, false
+			}
+		}
+	}
+	return v.Convert(t), true
+}
+
+func isSignedKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return true
+	}
+	return false
+}
+
+func isUintKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return true
+	}
+	return false
+}
+
+func isNumericKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// SetDefault sets the default value for a config key, overriding the value
+// from the config object.  Defaults have the lowest precedence, below the
+// config file, environment variables and flags, so this allows defaults to be
+// computed at runtime without modifying the config object.  It must be called
+// before loading the config.
+func (a *Amalgam) SetDefault(key string, value interface{}) {
+	if field, _, ok := a.lookupField(key); ok {
+		if a.defaults == nil {
+			a.defaults = make(map[string]interface{})
+		}
+		a.defaults[field] = value
+	}
+	a.viper.SetDefault(key, value)
+}
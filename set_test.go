@@ -0,0 +1,248 @@
+package amalgam
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+type settingsStore struct {
+	Name    string
+	Port    int
+	Workers uint8
+	Token   string
+}
+
+func TestSet(t *testing.T) {
+	var config settingsStore
+	a := newTestAmalgam(t, &config, nil)
+	loadYAML(t, a, "name: svc\n")
+
+	if err := a.Set("port", 9090); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Set("Workers", 8); err != nil {
+		t.Fatal(err)
+	}
+	if config.Port != 9090 || config.Workers != 8 {
+		t.Errorf("got %+v, want the values given to Set", config)
+	}
+	if got, err := a.GetInt64E("port"); err != nil || got != 9090 {
+		t.Errorf("GetInt64E(port) = %d, %v, want 9090", got, err)
+	}
+
+	for _, test := range []struct {
+		key   string
+		value interface{}
+		want  string
+	}{
+		{"name", 3, `cannot set config key "Name" of type string to a value of type int`},
+		{"workers", 300, `cannot set config key "Workers" of type uint8 to 300`},
+		{"workers", -1, `cannot set config key "Workers" of type uint8 to -1`},
+		{"port", 1.5, `cannot set config key "Port" of type int to 1.5`},
+		{"port", nil, `cannot set config key "Port" to nil`},
+		{"missing", 1, `unknown config key "missing"`},
+	} {
+		assertError(t, a.Set(test.key, test.value), test.want)
+	}
+	if config.Port != 9090 || config.Workers != 8 {
+		t.Errorf("got %+v, want invalid values not to be set", config)
+	}
+}
+
+func TestPersist(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+	path := writeFile(t, dir, "config.yaml", "name: svc\nport: 8080\nlegacy: kept\n")
+	defer setEnv("TOKEN", "from-env")()
+
+	var config settingsStore
+	a := newTestAmalgam(t, &config, []string{"--config", path, "--workers", "4"})
+	if err := a.LoadFile(); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Set("port", 9090); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Persist(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The flag and env values aren't written, while the file's other keys
+	// are kept.
+	want := map[string]interface{}{"name": "svc", "port": 9090, "legacy": "kept"}
+	if got := readYAMLFile(t, path); !reflect.DeepEqual(got, want) {
+		t.Errorf("persisted %v, want %v", got, want)
+	}
+}
+
+func TestAutoPersist(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+	path := filepath.Join(dir, "config.yaml")
+
+	var config settingsStore
+	a := newTestAmalgam(t, &config, []string{"--config", path}, WithAutoPersist())
+	assertError(t, a.Set("name", "early"), "no config file has been loaded")
+
+	writeFile(t, dir, "config.yaml", "name: svc\n")
+	if err := a.LoadFile(); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Set("name", "renamed"); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]interface{}{"name": "renamed"}
+	if got := readYAMLFile(t, path); !reflect.DeepEqual(got, want) {
+		t.Errorf("persisted %v, want %v", got, want)
+	}
+}
+
+func TestPersistKeepsFileKeys(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+	path := writeFile(t, dir, "config.yaml", "Port: 8080\nmax_conns: 5\nLabels:\n  MyKey: a\n  mykey: b\n")
+
+	var config struct {
+		Port     int
+		MaxConns int
+		Labels   map[string]string
+	}
+	rewriter := func(key string) string { return strings.Replace(key, "_", "", -1) }
+	a := newTestAmalgam(t, &config, []string{"--config", path}, WithCaseSensitiveKeys(), WithKeyRewriter(rewriter))
+	if err := a.LoadFile(); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Set("maxconns", 10); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Persist(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the set key changes, keeping the case and spelling of the others.
+	want := map[string]interface{}{
+		"Port":      8080,
+		"max_conns": 10,
+		"Labels":    map[interface{}]interface{}{"MyKey": "a", "mykey": "b"},
+	}
+	if got := readYAMLFile(t, path); !reflect.DeepEqual(got, want) {
+		t.Errorf("persisted %v, want %v", got, want)
+	}
+}
+
+func TestPersistJSON(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+	path := writeFile(t, dir, "config.json", `{"name": "svc", "port": 8080}`)
+
+	var config settingsStore
+	a := newTestAmalgam(t, &config, []string{"--config", path}, WithConfigType(""))
+	if err := a.LoadFile(); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Set("port", 9090); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Persist(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(data), "{\n  \"name\": \"svc\",\n  \"port\": 9090\n}\n"; got != want {
+		t.Errorf("persisted %q, want %q", got, want)
+	}
+}
+
+func TestPersistUnwritableFormat(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	for _, test := range []struct {
+		name, content, want string
+	}{
+		{"config.properties", "name = svc\n", `writing "properties" config isn't supported`},
+		{"config.yaml.gz", gzipData(t, "name: svc\n"), "writing compressed config isn't supported"},
+	} {
+		path := writeFile(t, dir, test.name, test.content)
+		var config settingsStore
+		a := newTestAmalgam(t, &config, []string{"--config", path}, WithConfigType(""))
+		if err := a.LoadFile(); err != nil {
+			t.Fatal(err)
+		}
+		if err := a.Set("name", "renamed"); err != nil {
+			t.Fatal(err)
+		}
+		assertError(t, a.Persist(), "cannot persist to "+path+": "+test.want)
+	}
+}
+
+func TestSetOverridesOnReload(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+	path := writeFile(t, dir, "config.yaml", "token: from-file\n")
+
+	var config struct {
+		Token string `amalgam:",env=MY_TOKEN"`
+	}
+	a := newTestAmalgam(t, &config, []string{"--config", path})
+	if err := a.LoadFile(); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Set("Token", "set"); err != nil {
+		t.Fatal(err)
+	}
+
+	defer setEnv("MY_TOKEN", "from-env")()
+	if err := a.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if config.Token != "set" {
+		t.Errorf("Token = %q, want the value given to Set over the environment", config.Token)
+	}
+}
+
+// readYAMLFile returns the settings in a YAML file.
+func readYAMLFile(t testing.TB, path string) map[string]interface{} {
+	t.Helper()
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	settings := make(map[string]interface{})
+	if err := yaml.Unmarshal(data, &settings); err != nil {
+		t.Fatal(err)
+	}
+	return settings
+}
+
+func TestSetDefault(t *testing.T) {
+	defer setEnv("WORKERS", "3")()
+
+	config := struct {
+		Name    string
+		Port    int
+		Workers int
+		Retries int
+	}{Name: "initial", Port: 1, Workers: 1, Retries: 1}
+	a := newTestAmalgam(t, &config, []string{"--retries", "4"})
+	a.SetDefault("name", "default")
+	a.SetDefault("Port", 2)
+	a.SetDefault("workers", 2)
+	a.SetDefault("retries", 2)
+	loadYAML(t, a, "port: 8080\n")
+
+	// The default replaces the config object's initial value, but the file,
+	// environment and flags take precedence over it.
+	if config.Name != "default" || config.Port != 8080 || config.Workers != 3 || config.Retries != 4 {
+		t.Errorf("got %+v, want the default below the file, env and flag", config)
+	}
+}
@@ -0,0 +1,143 @@
+package amalgam
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// keyPath splits a dotted field name into the path of a nested settings map,
+// which viper keys in lower case.
+func keyPath(field string) []string {
+	return strings.Split(strings.ToLower(field), ".")
+}
+
+// lookupPath returns the value at the path in a nested settings map.
+func lookupPath(settings map[string]interface{}, path []string) (interface{}, bool) {
+	for len(path) > 1 {
+		next, ok := settings[path[0]].(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		settings, path = next, path[1:]
+	}
+	value, ok := settings[path[0]]
+	return value, ok
+}
+
+// setPath sets the value at the path in a nested settings map, creating
+// intermediate maps as required.
+func setPath(settings map[string]interface{}, path []string, value interface{}) {
+	for len(path) > 1 {
+		next, ok := settings[path[0]].(map[string]interface{})
+		if !ok {
+			next = make(map[string]interface{})
+			settings[path[0]] = next
+		}
+		settings, path = next, path[1:]
+	}
+	settings[path[0]] = value
+}
+
+// deletePath removes the value at the path from a nested settings map.
+func deletePath(settings map[string]interface{}, path []string) {
+	for len(path) > 1 {
+		next, ok := settings[path[0]].(map[string]interface{})
+		if !ok {
+			return
+		}
+		settings, path = next, path[1:]
+	}
+	delete(settings, path[0])
+}
+
+// setValue applies a --set override.  Keys with list indexes (eg.
+// `servers.0.port`) update a single element of a list in the config file,
+// leaving the rest of the list as it is.  The updated lists are kept in
+// lists, keyed by their lowercased path, so that several elements can be
+// updated.
+func (a *Amalgam) setValue(key, value string, lists map[string]interface{}) error {
+	path := strings.Split(key, ".")
+	i := 0
+	for i < len(path) && !isIndex(path[i]) {
+		i++
+	}
+	if i == len(path) {
+		a.viper.Set(key, value)
+		return nil
+	}
+
+	listKey := strings.Join(path[:i], ".")
+	list, ok := lists[strings.ToLower(listKey)]
+	if !ok {
+		if list, ok = lookupPath(a.fileSettings, keyPath(listKey)); !ok {
+			return fmt.Errorf("invalid --set key %q: no list at %s in the config file", key, listKey)
+		}
+	}
+	updated, err := setIndexed(list, path[i:], value)
+	if err != nil {
+		return fmt.Errorf("invalid --set key %q: %v", key, err)
+	}
+	lists[strings.ToLower(listKey)] = updated
+	a.viper.Set(listKey, updated)
+	return nil
+}
+
+func isIndex(s string) bool {
+	_, err := strconv.Atoi(s)
+	return err == nil
+}
+
+// setIndexed returns a copy of a settings value with the value at the path
+// replaced, where the path may index into lists.
+func setIndexed(settings interface{}, path []string, value string) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+
+	if isIndex(path[0]) {
+		list, ok := settings.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%s indexes a value which isn't a list", path[0])
+		}
+		i, _ := strconv.Atoi(path[0])
+		if i < 0 || i >= len(list) {
+			return nil, fmt.Errorf("index %d out of range (the list has %d items)", i, len(list))
+		}
+
+		item, err := setIndexed(list[i], path[1:], value)
+		if err != nil {
+			return nil, err
+		}
+		list = append([]interface{}(nil), list...)
+		list[i] = item
+		return list, nil
+	}
+
+	m := make(map[string]interface{})
+	if settings != nil {
+		nested, ok := settingsMap(settings)
+		if !ok {
+			return nil, fmt.Errorf("%s is a key of a value which isn't a map", path[0])
+		}
+		for k, v := range nested {
+			m[k] = v
+		}
+	}
+
+	// Keys in lists aren't lowercased by viper, so match them as the
+	// decoder does.
+	key := strings.ToLower(path[0])
+	for k := range m {
+		if strings.EqualFold(k, key) {
+			key = k
+			break
+		}
+	}
+	item, err := setIndexed(m[key], path[1:], value)
+	if err != nil {
+		return nil, err
+	}
+	m[key] = item
+	return m, nil
+}
@@ -0,0 +1,36 @@
+package amalgam
+
+import (
+	"strings"
+	"testing"
+)
+
+type serversConfig struct {
+	Servers []struct {
+		Host string
+		Port int
+	}
+}
+
+func TestSetIndexedKey(t *testing.T) {
+	var config serversConfig
+	a := newTestAmalgam(t, &config, []string{"--set", "servers.1.port=9090", "--set", "servers.0.host=primary.local"}, WithSetFlag())
+	loadYAML(t, a, "servers:\n  - host: a.local\n    port: 80\n  - host: b.local\n    port: 81\n")
+
+	if len(config.Servers) != 2 {
+		t.Fatalf("Servers = %+v, want both servers", config.Servers)
+	}
+	if s := config.Servers[0]; s.Host != "primary.local" || s.Port != 80 {
+		t.Errorf("Servers[0] = %+v, want only its host overridden", s)
+	}
+	if s := config.Servers[1]; s.Host != "b.local" || s.Port != 9090 {
+		t.Errorf("Servers[1] = %+v, want only its port overridden", s)
+	}
+}
+
+func TestSetIndexedKeyOutOfRange(t *testing.T) {
+	var config serversConfig
+	a := newTestAmalgam(t, &config, []string{"--set", "servers.2.port=9090"}, WithSetFlag())
+	err := a.Load(strings.NewReader("servers:\n  - host: a.local\n"))
+	assertError(t, err, `invalid --set key "servers.2.port": index 2 out of range (the list has 1 items)`)
+}
@@ -0,0 +1,74 @@
+package amalgam
+
+import "reflect"
+
+// SliceMergeStrategy is how lists combine when several config files are
+// merged (see WithSliceMergeStrategy).
+type SliceMergeStrategy int
+
+const (
+	// SliceReplace replaces a list with the list from the later file.
+	SliceReplace SliceMergeStrategy = iota
+	// SliceAppend appends the items from the later file to the list.
+	SliceAppend
+	// SliceAppendUnique appends the items from the later file which aren't
+	// already in the list.
+	SliceAppendUnique
+)
+
+// WithSliceMergeStrategy sets how lists combine when config files are merged
+// (eg. by LoadGlob, WithLayeredFiles or WithIncludes).  By default, a later
+// file's list replaces the earlier one.
+func WithSliceMergeStrategy(strategy SliceMergeStrategy) func(*Amalgam) {
+	return func(a *Amalgam) {
+		a.sliceMerge = strategy
+	}
+}
+
+// mergeDocs is like mergeSettings, but combines lists with the slice merge
+// strategy.  It's used to merge the settings of config files.
+func (a *Amalgam) mergeDocs(dst, src map[string]interface{}) map[string]interface{} {
+	if a.sliceMerge == SliceReplace {
+		return mergeSettings(dst, src)
+	}
+
+	merged := make(map[string]interface{}, len(dst)+len(src))
+	for key, value := range dst {
+		merged[key] = value
+	}
+	for key, value := range src {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			existing, _ := merged[key].(map[string]interface{})
+			value = a.mergeDocs(existing, v)
+		case []interface{}:
+			if existing, ok := merged[key].([]interface{}); ok {
+				value = a.appendItems(existing, v)
+			}
+		}
+		merged[key] = value
+	}
+	return merged
+}
+
+// appendItems returns a new list of the items of list followed by the new
+// items, leaving out those already present with SliceAppendUnique.
+func (a *Amalgam) appendItems(list, items []interface{}) []interface{} {
+	appended := append([]interface{}(nil), list...)
+	for _, item := range items {
+		if a.sliceMerge == SliceAppendUnique && containsItem(appended, item) {
+			continue
+		}
+		appended = append(appended, item)
+	}
+	return appended
+}
+
+func containsItem(list []interface{}, item interface{}) bool {
+	for _, existing := range list {
+		if reflect.DeepEqual(existing, item) {
+			return true
+		}
+	}
+	return false
+}
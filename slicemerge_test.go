@@ -0,0 +1,38 @@
+package amalgam
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSliceMergeStrategies(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+	writeFile(t, dir, "10-base.yaml", "hosts: [a, b]\nname: base\n")
+	writeFile(t, dir, "20-extra.yaml", "hosts: [b, c]\n")
+
+	for _, test := range []struct {
+		strategy SliceMergeStrategy
+		want     []string
+	}{
+		{SliceReplace, []string{"b", "c"}},
+		{SliceAppend, []string{"a", "b", "b", "c"}},
+		{SliceAppendUnique, []string{"a", "b", "c"}},
+	} {
+		var config struct {
+			Hosts []string
+			Name  string
+		}
+		a := newTestAmalgam(t, &config, nil, WithConfigType(""), WithSliceMergeStrategy(test.strategy))
+		if err := a.LoadGlob(filepath.Join(dir, "*.yaml")); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(config.Hosts, test.want) {
+			t.Errorf("strategy %d: Hosts = %q, want %q", test.strategy, config.Hosts, test.want)
+		}
+		if config.Name != "base" {
+			t.Errorf("strategy %d: Name = %q, want base", test.strategy, config.Name)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package amalgam
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+)
+
+// LoadUnixSocket hydrates the config from a local agent listening on a Unix
+// domain socket, reading everything it writes until it closes the
+// connection.  The format is the one given by WithConfigType, or else YAML
+// (which also accepts JSON).  The context bounds connecting and reading.
+func (a *Amalgam) LoadUnixSocket(ctx context.Context, path string) error {
+	return a.named(a.loadUnixSocket(ctx, path))
+}
+
+// loadUnixSocket does the work of LoadUnixSocket.
+func (a *Amalgam) loadUnixSocket(ctx context.Context, path string) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "unix", path)
+	if err != nil {
+		return fmt.Errorf("connecting to config socket %s: %v", path, err)
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	data, err := ioutil.ReadAll(conn)
+	if err != nil {
+		if ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		return fmt.Errorf("reading config from socket %s: %v", path, err)
+	}
+
+	format := a.configType
+	if format == "" {
+		format = defaultStdinFormat
+	}
+	return a.load(bytes.NewReader(data), format)
+}
@@ -0,0 +1,85 @@
+package amalgam
+
+import (
+	"context"
+	"io"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// serveSocket listens on a Unix socket in the directory, writing the config
+// to each connection and closing it.
+func serveSocket(t *testing.T, dir, config string) (string, func()) {
+	t.Helper()
+	path := filepath.Join(dir, "agent.sock")
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		t.Skipf("unix sockets unavailable: %v", err)
+	}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			io.WriteString(conn, config)
+			conn.Close()
+		}
+	}()
+	return path, func() { l.Close() }
+}
+
+func TestLoadUnixSocket(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+	path, stop := serveSocket(t, dir, "name: from-agent\nport: 8080\n")
+	defer stop()
+
+	var config struct {
+		Name string
+		Port int
+	}
+	a := newTestAmalgam(t, &config, []string{"--port", "9090"}, WithConfigType(""))
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := a.LoadUnixSocket(ctx, path); err != nil {
+		t.Fatal(err)
+	}
+
+	if config.Name != "from-agent" || config.Port != 9090 {
+		t.Errorf("got %+v, want the agent's config with the flag on top", config)
+	}
+}
+
+func TestLoadUnixSocketErrors(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+
+	var config struct {
+		Name string
+	}
+	a := newTestAmalgam(t, &config, nil)
+	missing := filepath.Join(dir, "missing.sock")
+	assertError(t, a.LoadUnixSocket(context.Background(), missing), "connecting to config socket "+missing)
+
+	// An agent which never responds.
+	path := filepath.Join(dir, "silent.sock")
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		t.Skipf("unix sockets unavailable: %v", err)
+	}
+	defer l.Close()
+	go func() {
+		conn, err := l.Accept()
+		if err == nil {
+			defer conn.Close()
+			time.Sleep(time.Second)
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assertError(t, a.LoadUnixSocket(ctx, path), "reading config from socket "+path)
+}
@@ -0,0 +1,34 @@
+package amalgam
+
+import (
+	"errors"
+	"io"
+	"os"
+)
+
+// stdin is the reader used for a config file of `-`.
+var stdin io.Reader = os.Stdin
+
+// defaultStdinFormat is the format of config read from stdin, unless one is
+// given by WithConfigType.  YAML also accepts JSON.
+const defaultStdinFormat = "yaml"
+
+// loadStdin hydrates the config from stdin, refusing to wait for input from
+// a terminal.
+func (a *Amalgam) loadStdin() error {
+	if f, ok := stdin.(*os.File); ok {
+		fi, err := f.Stat()
+		if err != nil {
+			return err
+		}
+		if fi.Mode()&os.ModeCharDevice != 0 {
+			return errors.New("config file is - but stdin is a terminal, expected piped config")
+		}
+	}
+
+	format := a.configType
+	if format == "" {
+		format = defaultStdinFormat
+	}
+	return a.load(stdin, format)
+}
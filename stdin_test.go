@@ -0,0 +1,34 @@
+package amalgam
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestLoadFileFromStdin(t *testing.T) {
+	for _, test := range []struct {
+		configType string
+		input      string
+	}{
+		{"", "name: piped\nport: 8080\n"},
+		{"json", `{"name": "piped", "port": 8080}`},
+	} {
+		func() {
+			defer func(r io.Reader) { stdin = r }(stdin)
+			stdin = strings.NewReader(test.input)
+
+			var config struct {
+				Name string
+				Port int
+			}
+			a := newTestAmalgam(t, &config, []string{"--config", "-"}, WithConfigType(test.configType))
+			if err := a.LoadFile(); err != nil {
+				t.Fatal(err)
+			}
+			if config.Name != "piped" || config.Port != 8080 {
+				t.Errorf("format %q: got %+v, want the piped config", test.configType, config)
+			}
+		}()
+	}
+}
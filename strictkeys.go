@@ -0,0 +1,69 @@
+package amalgam
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// WithStrictKeys rejects keys in the config file which don't correspond to a
+// field (eg. typos), reporting them all in an ErrorList.  The keys within map
+// fields are dynamic, so aren't checked, and nor are the items of slices.
+func WithStrictKeys() func(*Amalgam) {
+	return func(a *Amalgam) {
+		a.strictKeys = true
+	}
+}
+
+// checkUnknownKeys returns an ErrorList of the keys in the config file which
+// don't correspond to a field.
+func (a *Amalgam) checkUnknownKeys() error {
+	unknown := a.unknownKeys(a.fileSettings, "")
+	sort.Strings(unknown)
+
+	var errs ErrorList
+	for _, key := range unknown {
+		errs = append(errs, fmt.Errorf("unknown config key %q", key))
+	}
+	return errs.err()
+}
+
+// unknownKeys returns the paths of the keys under the field path prefix which
+// don't correspond to a field, descending into nested structs.  Keys which
+// are collected by a field tagged with `remain` aren't unknown.
+func (a *Amalgam) unknownKeys(settings map[string]interface{}, prefix string) []string {
+	var unknown []string
+	remain := a.remainField(prefix) != ""
+	for key, value := range settings {
+		path := key
+		if prefix != "" {
+			path = prefix + "." + key
+		} else if key == versionKey && a.configVersion != 0 {
+			continue
+		}
+
+		if _, _, ok := a.lookupField(path); ok {
+			continue
+		}
+		if nested, ok := value.(map[string]interface{}); ok && a.hasFieldsUnder(path) {
+			unknown = append(unknown, a.unknownKeys(nested, path)...)
+			continue
+		}
+		if !remain {
+			unknown = append(unknown, path)
+		}
+	}
+	return unknown
+}
+
+// hasFieldsUnder reports whether there are fields nested under the path, ie.
+// it's a nested struct.
+func (a *Amalgam) hasFieldsUnder(path string) bool {
+	prefix := strings.ToLower(path) + "."
+	for field := range a.fields {
+		if strings.HasPrefix(strings.ToLower(field), prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,38 @@
+package amalgam
+
+import (
+	"strings"
+	"testing"
+)
+
+type strictKeysConfig struct {
+	Name     string
+	Labels   map[string]string
+	Backends map[string]struct {
+		Host string
+	}
+	Database struct {
+		Port int
+	}
+}
+
+func TestStrictKeys(t *testing.T) {
+	var config strictKeysConfig
+	a := newTestAmalgam(t, &config, nil, WithStrictKeys())
+	loadYAML(t, a, "name: svc\nlabels:\n  team: core\n  anything: x\nbackends:\n  primary:\n    host: a\n  replica:\n    host: b\ndatabase:\n  port: 5432\n")
+
+	if len(config.Labels) != 2 || len(config.Backends) != 2 {
+		t.Errorf("got %+v, want the map fields' dynamic keys", config)
+	}
+}
+
+func TestStrictKeysTypos(t *testing.T) {
+	var config strictKeysConfig
+	a := newTestAmalgam(t, &config, nil, WithStrictKeys())
+	err := a.Load(strings.NewReader("nmae: svc\nlabels:\n  team: core\ndatabase:\n  prot: 5432\n"))
+	assertError(t, err, `unknown config key "nmae"`)
+	assertError(t, err, `unknown config key "database.prot"`)
+	if strings.Contains(err.Error(), "team") {
+		t.Errorf("got error %q, want the map's keys allowed", err)
+	}
+}
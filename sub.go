@@ -0,0 +1,36 @@
+package amalgam
+
+import (
+	"fmt"
+
+	"github.com/mitchellh/mapstructure"
+)
+
+// LoadInto decodes the resolved settings under a dotted key prefix (eg.
+// `Plugins.Auth`) into a separate object, such as a plugin's own config
+// struct.  It uses the already-loaded settings, so it should be called after
+// one of the Load methods.
+func (a *Amalgam) LoadInto(sub interface{}, prefix string) error {
+	return a.named(a.loadInto(sub, prefix))
+}
+
+// loadInto does the work of LoadInto.
+func (a *Amalgam) loadInto(sub interface{}, prefix string) error {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	value, ok := lookupPath(a.viper.AllSettings(), keyPath(prefix))
+	if !ok {
+		return fmt.Errorf("unknown config prefix %q", prefix)
+	}
+	if _, ok := value.(map[string]interface{}); !ok {
+		return fmt.Errorf("config prefix %q is not a nested section", prefix)
+	}
+
+	decoder, err := mapstructure.NewDecoder(a.decoderConfig(sub))
+	if err != nil {
+		return err
+	}
+
+	return decoder.Decode(value)
+}
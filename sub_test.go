@@ -0,0 +1,33 @@
+package amalgam
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLoadInto(t *testing.T) {
+	var config struct {
+		Name    string
+		Plugins map[string]interface{}
+	}
+	a := newTestAmalgam(t, &config, nil)
+	loadYAML(t, a, "name: svc\nplugins:\n  auth:\n    issuer: https://auth.local\n    ttl: 5m\n    scopes: read,write\n")
+
+	var auth struct {
+		Issuer string
+		TTL    time.Duration
+		Scopes []string
+	}
+	if err := a.LoadInto(&auth, "plugins.auth"); err != nil {
+		t.Fatal(err)
+	}
+	if auth.Issuer != "https://auth.local" || auth.TTL != 5*time.Minute {
+		t.Errorf("got %+v, want the plugins.auth subtree", auth)
+	}
+	if len(auth.Scopes) != 2 {
+		t.Errorf("Scopes = %v, want the list split by the decode hooks", auth.Scopes)
+	}
+
+	assertError(t, a.LoadInto(&auth, "plugins.missing"), `unknown config prefix "plugins.missing"`)
+	assertError(t, a.LoadInto(&auth, "name"), `config prefix "name" is not a nested section`)
+}
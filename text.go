@@ -0,0 +1,171 @@
+package amalgam
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/spf13/pflag"
+)
+
+var (
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	flagValueType       = reflect.TypeOf((*pflag.Value)(nil)).Elem()
+)
+
+// isValueType reports whether values of the type can be parsed from a
+// string by the type itself, ie. a pointer to it implements pflag.Value or
+// encoding.TextUnmarshaler, or by a parser registered with RegisterParser.
+func isValueType(t reflect.Type) bool {
+	ptr := reflect.PtrTo(t)
+	return ptr.Implements(flagValueType) || ptr.Implements(textUnmarshalerType) || typeParser(t) != nil
+}
+
+// newFlagValue returns the flag value for a field whose type implements
+// pflag.Value or encoding.TextUnmarshaler, initialised with the field's
+// value, or nil for other fields.  pflag.Value takes precedence for flags,
+// while the decode hook prefers encoding.TextUnmarshaler, so types
+// implementing both should parse the same strings with each.
+func newFlagValue(info fieldInfo) pflag.Value {
+	t := info.value.Type()
+	if !isValueType(t) {
+		return nil
+	}
+
+	ptr := reflect.New(t)
+	ptr.Elem().Set(info.value)
+	if v, ok := ptr.Interface().(pflag.Value); ok && typeParser(t) == nil {
+		return v
+	}
+	return &textValue{ptr}
+}
+
+// textValue is a pflag.Value for a type implementing encoding.TextUnmarshaler,
+// or with a registered parser.
+type textValue struct {
+	ptr reflect.Value
+}
+
+func (v *textValue) Set(val string) error {
+	parsed, err := parseValue(v.ptr.Elem().Type(), val)
+	if err != nil {
+		return err
+	}
+	v.ptr.Elem().Set(parsed)
+	return nil
+}
+
+func (v *textValue) Type() string {
+	return v.ptr.Elem().Type().Name()
+}
+
+func (v *textValue) String() string {
+	return formatValue(v.ptr)
+}
+
+// formatValue renders the value pointed to by ptr, using
+// encoding.TextMarshaler if implemented, or else fmt.Stringer.
+func formatValue(ptr reflect.Value) string {
+	switch value := ptr.Interface().(type) {
+	case encoding.TextMarshaler:
+		text, err := value.MarshalText()
+		if err != nil {
+			return ""
+		}
+		return string(text)
+	case fmt.Stringer:
+		return value.String()
+	}
+	return fmt.Sprint(ptr.Elem().Interface())
+}
+
+// parseValue parses a string into a value of a type which parses itself,
+// using its registered parser if any, encoding.TextUnmarshaler if
+// implemented, or else pflag.Value.
+func parseValue(t reflect.Type, s string) (reflect.Value, error) {
+	if parse := typeParser(t); parse != nil {
+		return parseRegistered(t, parse, s)
+	}
+
+	ptr := reflect.New(t)
+	switch value := ptr.Interface().(type) {
+	case encoding.TextUnmarshaler:
+		if err := value.UnmarshalText([]byte(s)); err != nil {
+			return reflect.Value{}, err
+		}
+	case pflag.Value:
+		if err := value.Set(s); err != nil {
+			return reflect.Value{}, err
+		}
+	}
+	return ptr.Elem(), nil
+}
+
+// textSliceValue is a pflag.Value for a slice of a type which parses itself
+// (see isValueType).  Items are comma-separated, and repeating the flag
+// appends to the slice.
+type textSliceValue struct {
+	value   reflect.Value
+	changed bool
+}
+
+func newTextSliceValue(val reflect.Value) *textSliceValue {
+	value := reflect.MakeSlice(val.Type(), val.Len(), val.Len())
+	reflect.Copy(value, val)
+	return &textSliceValue{value: value}
+}
+
+func (s *textSliceValue) Set(val string) error {
+	list, err := splitList(val, ",")
+	if err != nil {
+		return err
+	}
+
+	items := reflect.MakeSlice(s.value.Type(), 0, len(list))
+	for _, item := range list {
+		v, err := parseValue(s.value.Type().Elem(), item)
+		if err != nil {
+			return err
+		}
+		items = reflect.Append(items, v)
+	}
+
+	if s.changed {
+		s.value = reflect.AppendSlice(s.value, items)
+	} else {
+		s.value = items
+	}
+	s.changed = true
+
+	return nil
+}
+
+func (s *textSliceValue) Type() string {
+	return s.value.Type().Elem().Name() + "Slice"
+}
+
+func (s *textSliceValue) String() string {
+	items := make([]string, s.value.Len())
+	for i := range items {
+		items[i] = formatValue(s.value.Index(i).Addr())
+	}
+	return "[" + strings.Join(items, ",") + "]"
+}
+
+// stringToValueHookFunc converts strings to types which parse themselves,
+// using encoding.TextUnmarshaler if implemented, or else pflag.Value.
+func stringToValueHookFunc() mapstructure.DecodeHookFuncType {
+	return func(f reflect.Type, t reflect.Type, data interface{}) (interface{}, error) {
+		if f.Kind() != reflect.String || !isValueType(t) {
+			return data, nil
+		}
+
+		v, err := parseValue(t, reflect.ValueOf(data).String())
+		if err != nil {
+			return nil, err
+		}
+		return v.Interface(), nil
+	}
+}
@@ -0,0 +1,104 @@
+package amalgam
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+)
+
+// zapLevel mimics zapcore.Level, which implements both pflag.Value and
+// encoding.TextUnmarshaler.
+type zapLevel int8
+
+const (
+	debugLevel zapLevel = iota - 1
+	infoLevel
+	warnLevel
+)
+
+func (l zapLevel) String() string {
+	switch l {
+	case debugLevel:
+		return "debug"
+	case infoLevel:
+		return "info"
+	case warnLevel:
+		return "warn"
+	}
+	return fmt.Sprintf("level(%d)", l)
+}
+
+func (l *zapLevel) UnmarshalText(text []byte) error {
+	switch strings.ToLower(string(text)) {
+	case "debug":
+		*l = debugLevel
+	case "info", "":
+		*l = infoLevel
+	case "warn":
+		*l = warnLevel
+	default:
+		return fmt.Errorf("unrecognized level: %q", text)
+	}
+	return nil
+}
+
+func (l *zapLevel) Set(s string) error {
+	return l.UnmarshalText([]byte(s))
+}
+
+func (l *zapLevel) Type() string {
+	return "level"
+}
+
+func TestLevelFromEachSource(t *testing.T) {
+	type levelConfig struct {
+		Level zapLevel
+	}
+	for _, test := range []struct {
+		source string
+		args   []string
+		env    string
+		file   string
+	}{
+		{source: "flag", args: []string{"--level", "WARN"}},
+		{source: "env", env: "WARN"},
+		{source: "file", file: "level: WARN\n"},
+	} {
+		config := levelConfig{Level: debugLevel}
+		if test.env != "" {
+			os.Setenv("LEVEL", test.env)
+		}
+		a := newTestAmalgam(t, &config, test.args)
+		err := a.Load(strings.NewReader(test.file))
+		os.Unsetenv("LEVEL")
+		if err != nil {
+			t.Errorf("%s: %v", test.source, err)
+			continue
+		}
+		if config.Level != warnLevel {
+			t.Errorf("%s: Level = %v, want warn", test.source, config.Level)
+		}
+	}
+}
+
+func TestLevelDefaultAndError(t *testing.T) {
+	config := struct {
+		Level zapLevel
+	}{Level: debugLevel}
+	a := newTestAmalgam(t, &config, nil)
+	if flag := a.flagSet.Lookup("level"); flag == nil || flag.DefValue != "debug" || flag.Value.Type() != "level" {
+		t.Errorf("got flag %+v, want the type's own pflag.Value", flag)
+	}
+	loadYAML(t, a, "")
+	if config.Level != debugLevel {
+		t.Errorf("Level = %v, want the default", config.Level)
+	}
+
+	for _, a := range []*Amalgam{
+		newTestAmalgam(t, &config, []string{"--level", "loud"}),
+		newTestAmalgam(t, &config, nil),
+	} {
+		assertError(t, a.Load(strings.NewReader("level: loud\n")), `unrecognized level: "loud"`)
+	}
+}
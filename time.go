@@ -0,0 +1,178 @@
+package amalgam
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/mitchellh/mapstructure"
+)
+
+// timeLayout returns the layout used to parse and format the field's times,
+// from the `layout` tag option.  The default is RFC 3339.
+func (f fieldInfo) timeLayout() string {
+	if layout := f.options["layout"]; layout != "" {
+		return layout
+	}
+	return time.RFC3339
+}
+
+// parseTime parses a time with the layout.  All time parsing goes through
+// here, so that flags, the environment and config files are consistent.
+func parseTime(layout, s string) (time.Time, error) {
+	t, err := time.Parse(layout, strings.TrimSpace(s))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q, expected layout %q", s, layout)
+	}
+	return t, nil
+}
+
+// formatTime formats a time with the layout, rendering the zero time as an
+// empty string.
+func formatTime(layout string, t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(layout)
+}
+
+// convertTimes parses the string values for a time.Time, []time.Time or
+// map[string]time.Time field with the layout.  Values of any other type are
+// returned unchanged.
+func convertTimes(t reflect.Type, layout string, raw interface{}) (interface{}, error) {
+	switch {
+	case t == timeType:
+		if s, ok := raw.(string); ok {
+			return parseTime(layout, s)
+		}
+	case t.Kind() == reflect.Slice && t.Elem() == timeType:
+		var items []interface{}
+		switch v := raw.(type) {
+		case string:
+			list, err := splitList(v, ",")
+			if err != nil {
+				return nil, err
+			}
+			for _, s := range list {
+				items = append(items, s)
+			}
+		case []interface{}:
+			items = v
+		default:
+			return raw, nil
+		}
+
+		times := make([]time.Time, 0, len(items))
+		for _, item := range items {
+			value, err := convertTimes(timeType, layout, item)
+			if err != nil {
+				return nil, err
+			}
+			tm, ok := value.(time.Time)
+			if !ok {
+				return nil, fmt.Errorf("invalid time %v", item)
+			}
+			times = append(times, tm)
+		}
+		return times, nil
+	case t.Kind() == reflect.Map && t.Elem() == timeType:
+		if m, ok := raw.(map[string]interface{}); ok {
+			times := make(map[string]interface{}, len(m))
+			for key, item := range m {
+				value, err := convertTimes(timeType, layout, item)
+				if err != nil {
+					return nil, fmt.Errorf("%s: %v", key, err)
+				}
+				times[key] = value
+			}
+			return times, nil
+		}
+	}
+
+	return raw, nil
+}
+
+// stringToTimeHookFunc converts strings to a time.Time with the layout, for
+// times which aren't config fields in their own right (eg. in a slice of
+// structs).
+func stringToTimeHookFunc(layout string) mapstructure.DecodeHookFuncType {
+	return func(f reflect.Type, t reflect.Type, data interface{}) (interface{}, error) {
+		if f.Kind() != reflect.String || t != timeType {
+			return data, nil
+		}
+
+		return parseTime(layout, data.(string))
+	}
+}
+
+// timeValue is a pflag.Value for a time.Time flag.
+type timeValue struct {
+	value  time.Time
+	layout string
+}
+
+func newTimeValue(val time.Time, layout string) *timeValue {
+	return &timeValue{value: val, layout: layout}
+}
+
+func (v *timeValue) Set(val string) error {
+	t, err := parseTime(v.layout, val)
+	if err != nil {
+		return err
+	}
+	v.value = t
+	return nil
+}
+
+func (v *timeValue) Type() string {
+	return "time"
+}
+
+func (v *timeValue) String() string {
+	return formatTime(v.layout, v.value)
+}
+
+// timeSliceValue is a pflag.Value for a []time.Time flag.  Times are
+// comma-separated, and repeating the flag appends to the slice.
+type timeSliceValue struct {
+	value   []time.Time
+	layout  string
+	changed bool
+}
+
+func newTimeSliceValue(val []time.Time, layout string) *timeSliceValue {
+	return &timeSliceValue{value: val, layout: layout}
+}
+
+func (s *timeSliceValue) Set(val string) error {
+	var times []time.Time
+	for _, item := range strings.Split(val, ",") {
+		t, err := parseTime(s.layout, item)
+		if err != nil {
+			return err
+		}
+		times = append(times, t)
+	}
+
+	if s.changed {
+		s.value = append(s.value, times...)
+	} else {
+		s.value = times
+	}
+	s.changed = true
+
+	return nil
+}
+
+func (s *timeSliceValue) Type() string {
+	return "timeSlice"
+}
+
+func (s *timeSliceValue) String() string {
+	times := make([]string, len(s.value))
+	for i, t := range s.value {
+		times[i] = t.Format(s.layout)
+	}
+	return "[" + strings.Join(times, ",") + "]"
+}
@@ -0,0 +1,76 @@
+package amalgam
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type layoutConfig struct {
+	Start   time.Time            `amalgam:",layout=2006-01-02"`
+	Dates   []time.Time          `amalgam:",layout=2006-01-02"`
+	Windows map[string]time.Time `amalgam:",layout=2006-01-02"`
+}
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func TestTimeLayoutFromFile(t *testing.T) {
+	var config layoutConfig
+	a := newTestAmalgam(t, &config, nil)
+	loadYAML(t, a, "start: 2021-03-04\ndates: [\"2021-01-01\", \"2021-12-31\"]\nwindows:\n  open: \"2021-06-01\"\n")
+
+	checkLayoutConfig(t, config, layoutConfig{
+		Start:   date(2021, 3, 4),
+		Dates:   []time.Time{date(2021, 1, 1), date(2021, 12, 31)},
+		Windows: map[string]time.Time{"open": date(2021, 6, 1)},
+	})
+}
+
+func TestTimeLayoutFromFlags(t *testing.T) {
+	var config layoutConfig
+	a := newTestAmalgam(t, &config, []string{"--start", "2022-02-02", "--dates", "2022-01-01,2022-01-02"})
+	loadYAML(t, a, "")
+
+	checkLayoutConfig(t, config, layoutConfig{
+		Start: date(2022, 2, 2),
+		Dates: []time.Time{date(2022, 1, 1), date(2022, 1, 2)},
+	})
+}
+
+func TestTimeLayoutFromEnv(t *testing.T) {
+	defer setEnv("START", "2023-05-06")()
+	defer setEnv("DATES", "2023-01-01,2023-02-01")()
+	defer setEnv("WINDOWS", "open=2023-03-01")()
+
+	var config layoutConfig
+	a := newTestAmalgam(t, &config, nil)
+	loadYAML(t, a, "")
+
+	checkLayoutConfig(t, config, layoutConfig{
+		Start:   date(2023, 5, 6),
+		Dates:   []time.Time{date(2023, 1, 1), date(2023, 2, 1)},
+		Windows: map[string]time.Time{"open": date(2023, 3, 1)},
+	})
+}
+
+func TestTimeLayoutMismatch(t *testing.T) {
+	var config layoutConfig
+	a := newTestAmalgam(t, &config, nil)
+	assertError(t, a.Load(strings.NewReader("dates: [\"2021-01-01T00:00:00Z\"]\n")), `expected layout "2006-01-02"`)
+}
+
+func checkLayoutConfig(t *testing.T, got, want layoutConfig) {
+	t.Helper()
+	if !got.Start.Equal(want.Start) {
+		t.Errorf("Start = %v, want %v", got.Start, want.Start)
+	}
+	if !reflect.DeepEqual(got.Dates, want.Dates) {
+		t.Errorf("Dates = %v, want %v", got.Dates, want.Dates)
+	}
+	if !reflect.DeepEqual(got.Windows, want.Windows) {
+		t.Errorf("Windows = %v, want %v", got.Windows, want.Windows)
+	}
+}
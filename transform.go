@@ -0,0 +1,63 @@
+package amalgam
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// fieldTransform is a function applied to a field's value after loading.
+type fieldTransform struct {
+	field string
+	fn    func(interface{}) (interface{}, error)
+}
+
+// WithFieldTransform applies fn to the value of the field (the dotted field
+// path, eg. `Log.Dir`) after each load, and stores the result in the config
+// object, eg. to normalise a value.  The result must be of the field's type.
+// Transforms run in the order given, before the config is validated.
+func WithFieldTransform(field string, fn func(interface{}) (interface{}, error)) func(*Amalgam) {
+	return func(a *Amalgam) {
+		a.transforms = append(a.transforms, fieldTransform{field, fn})
+	}
+}
+
+// checkTransforms checks that the fields given to WithFieldTransform exist.
+func (a *Amalgam) checkTransforms() error {
+	for _, t := range a.transforms {
+		field, info, ok := a.lookupField(t.field)
+		if !ok {
+			return fmt.Errorf("unknown config key %q for transform", t.field)
+		}
+		if info.excluded() {
+			return fmt.Errorf("config key %q for transform is excluded", field)
+		}
+	}
+	return nil
+}
+
+// applyTransforms applies the field transforms to the config object,
+// skipping the fields in unchanged.
+func (a *Amalgam) applyTransforms(unchanged map[string]bool) error {
+	for _, t := range a.transforms {
+		field, info, _ := a.lookupField(t.field)
+		target := a.fieldValue(info)
+		if unchanged[field] || !target.CanSet() {
+			continue
+		}
+
+		result, err := t.fn(target.Interface())
+		if err != nil {
+			return fmt.Errorf("%s: %v", field, err)
+		}
+
+		v := reflect.ValueOf(result)
+		if !v.IsValid() {
+			v = reflect.Zero(target.Type())
+		}
+		if !v.Type().AssignableTo(target.Type()) {
+			return fmt.Errorf("%s: transform returned a value of type %s, expected %s", field, v.Type(), target.Type())
+		}
+		target.Set(v)
+	}
+	return nil
+}
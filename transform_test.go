@@ -0,0 +1,60 @@
+package amalgam
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/pflag"
+)
+
+// expandHome replaces a leading ~ in a path with the home directory.
+func expandHome(v interface{}) (interface{}, error) {
+	path := v.(string)
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path, nil
+	}
+	home := os.Getenv("HOME")
+	if home == "" {
+		return nil, errors.New("HOME is not set")
+	}
+	return filepath.Join(home, path[1:]), nil
+}
+
+func TestFieldTransform(t *testing.T) {
+	defer setEnv("HOME", "/home/user")()
+
+	var config struct {
+		Log struct {
+			Dir string
+		}
+		Cache string
+	}
+	a := newTestAmalgam(t, &config, nil, WithFieldTransform("Log.Dir", expandHome), WithFieldTransform("cache", expandHome))
+	loadYAML(t, a, "log:\n  dir: ~/logs\ncache: /var/cache\n")
+
+	if config.Log.Dir != "/home/user/logs" {
+		t.Errorf("Log.Dir = %q, want ~ expanded", config.Log.Dir)
+	}
+	if config.Cache != "/var/cache" {
+		t.Errorf("Cache = %q, want it unchanged", config.Cache)
+	}
+}
+
+func TestFieldTransformErrors(t *testing.T) {
+	var config struct {
+		Port int
+	}
+	toString := func(v interface{}) (interface{}, error) { return "80", nil }
+	a := newTestAmalgam(t, &config, nil, WithFieldTransform("port", toString))
+	assertError(t, a.Load(strings.NewReader("port: 80\n")), "Port: transform returned a value of type string, expected int")
+
+	failing := func(v interface{}) (interface{}, error) { return nil, errors.New("bad port") }
+	a = newTestAmalgam(t, &config, nil, WithFieldTransform("port", failing))
+	assertError(t, a.Load(strings.NewReader("port: 80\n")), "Port: bad port")
+
+	_, err := New(&config, WithFlagSet(pflag.NewFlagSet("test", pflag.ContinueOnError)), WithFieldTransform("missing", toString))
+	assertError(t, err, `unknown config key "missing" for transform`)
+}
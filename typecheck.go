@@ -0,0 +1,55 @@
+package amalgam
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/mitchellh/mapstructure"
+)
+
+// WithTypeChecks checks that each resolved value can be converted to the type
+// of its field before unmarshalling, so that a mismatch (eg. a string in the
+// config file for an int field) is reported against the key and value, rather
+// than as a decoder error.
+func WithTypeChecks() func(*Amalgam) {
+	return func(a *Amalgam) {
+		a.typeChecks = true
+	}
+}
+
+// checkTypes attempts to decode each resolved value into a value of its
+// field's type, and returns an ErrorList of the values which couldn't be.
+func (a *Amalgam) checkTypes() error {
+	var errs ErrorList
+	for _, field := range a.sortedFields() {
+		info := a.fields[field]
+		value := a.viper.Get(field)
+		if env, ok := a.exactEnvValue(field); ok {
+			value = env
+		}
+		if file, ok := a.fileValues[field]; ok {
+			value = file
+		}
+		if info.excluded() || value == nil {
+			continue
+		}
+
+		value, err := a.convertField(info, value)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %v", field, err))
+			continue
+		}
+
+		t := info.value.Type()
+		target := reflect.New(t)
+		decoder, err := mapstructure.NewDecoder(a.decoderConfig(target.Interface()))
+		if err != nil {
+			return err
+		}
+		if err := decoder.Decode(value); err != nil {
+			errs = append(errs, fmt.Errorf("%s: cannot use %#v as %s", field, value, t))
+		}
+	}
+
+	return errs.err()
+}
@@ -0,0 +1,31 @@
+package amalgam
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTypeChecks(t *testing.T) {
+	var config struct {
+		Port    int
+		Timeout int
+		Name    string
+	}
+	a := newTestAmalgam(t, &config, nil, WithTypeChecks())
+	err := a.Load(strings.NewReader("port: abc\ntimeout: 5\nname: svc\n"))
+	assertError(t, err, `Port: cannot use "abc" as int`)
+	if strings.Contains(err.Error(), "Timeout") || strings.Contains(err.Error(), "Name") {
+		t.Errorf("got error %q, want only the mismatched key reported", err)
+	}
+}
+
+func TestTypeChecksPass(t *testing.T) {
+	var config struct {
+		Port int
+	}
+	a := newTestAmalgam(t, &config, nil, WithTypeChecks())
+	loadYAML(t, a, "port: \"8080\"\n")
+	if config.Port != 8080 {
+		t.Errorf("Port = %d, want 8080", config.Port)
+	}
+}
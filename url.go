@@ -0,0 +1,103 @@
+package amalgam
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// defaultHTTPTimeout is the timeout for fetching config with LoadURL, unless
+// a client is given with WithHTTPClient.
+const defaultHTTPTimeout = 30 * time.Second
+
+// WithHTTPClient specifies the HTTP client used by LoadURL, eg. to customise
+// the TLS configuration or timeout.
+func WithHTTPClient(client *http.Client) func(*Amalgam) {
+	return func(a *Amalgam) {
+		a.httpClient = client
+	}
+}
+
+// LoadURL hydrates the config from the body of an HTTP(S) GET request.  The
+// format is the one given by WithConfigType, or else inferred from the URL's
+// file extension or the response's Content-Type.
+func (a *Amalgam) LoadURL(ctx context.Context, rawURL string) error {
+	return a.named(a.loadURL(ctx, rawURL))
+}
+
+// loadURL does the work of LoadURL.
+func (a *Amalgam) loadURL(ctx context.Context, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+
+	client := a.httpClient
+	if client == nil {
+		client = &http.Client{Timeout: defaultHTTPTimeout}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("fetching config from %s: %s", redactURL(u), resp.Status)
+	}
+
+	format := a.formatFor(u.Path)
+	if format == "" {
+		contentType := resp.Header.Get("Content-Type")
+		if format = formatForContentType(contentType); format == "" {
+			return fmt.Errorf("fetching config from %s: unknown config format for Content-Type %q", redactURL(u), contentType)
+		}
+	}
+
+	return a.load(resp.Body, format)
+}
+
+// formatForContentType returns the config format for a MIME type, or an empty
+// string if it isn't recognised.
+func formatForContentType(contentType string) string {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return ""
+	}
+
+	switch mediaType {
+	case "application/json":
+		return "json"
+	case "application/yaml", "application/x-yaml", "text/yaml", "text/x-yaml":
+		return "yaml"
+	case "application/toml":
+		return "toml"
+	}
+
+	if idx := strings.LastIndex(mediaType, "+"); idx >= 0 {
+		// eg. application/vnd.example+json
+		return formatForContentType("application/" + mediaType[idx+1:])
+	}
+	return ""
+}
+
+// redactURL returns the URL as a string, with any password replaced by
+// `xxxxx`, as url.URL.Redacted does in newer Go versions.
+func redactURL(u *url.URL) string {
+	if _, ok := u.User.Password(); !ok {
+		return u.String()
+	}
+	redacted := *u
+	redacted.User = url.UserPassword(u.User.Username(), "xxxxx")
+	return redacted.String()
+}
@@ -0,0 +1,70 @@
+package amalgam
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLoadURL(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/config":
+			w.Header().Set("Content-Type", "application/vnd.example+json; charset=utf-8")
+			w.Write([]byte(`{"name": "svc", "port": 8080}`))
+		case "/config.yaml":
+			w.Header().Set("Content-Type", "text/plain")
+			w.Write([]byte("name: from-yaml\n"))
+		case "/plain":
+			w.Header().Set("Content-Type", "text/plain")
+			w.Write([]byte("name=svc\n"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	var config struct {
+		Name string
+		Port int
+	}
+	a := newTestAmalgam(t, &config, []string{"--port", "9090"}, WithConfigType(""))
+	if err := a.LoadURL(context.Background(), srv.URL+"/config"); err != nil {
+		t.Fatal(err)
+	}
+	if config.Name != "svc" || config.Port != 9090 {
+		t.Errorf("got %+v, want the fetched name and the flag's port", config)
+	}
+
+	a = newTestAmalgam(t, &config, nil, WithConfigType(""))
+	if err := a.LoadURL(context.Background(), srv.URL+"/config.yaml"); err != nil {
+		t.Fatal(err)
+	}
+	if config.Name != "from-yaml" {
+		t.Errorf("Name = %q, want the format inferred from the URL", config.Name)
+	}
+
+	assertError(t, a.LoadURL(context.Background(), srv.URL+"/plain"), `unknown config format for Content-Type "text/plain"`)
+	assertError(t, a.LoadURL(context.Background(), srv.URL+"/missing"), "404 Not Found")
+}
+
+func TestLoadURLRedactsPassword(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "forbidden", http.StatusForbidden)
+	}))
+	defer srv.Close()
+
+	var config struct {
+		Name string
+	}
+	a := newTestAmalgam(t, &config, nil)
+	rawURL := strings.Replace(srv.URL, "http://", "http://admin:hunter2@", 1) + "/config.json"
+	err := a.LoadURL(context.Background(), rawURL)
+	assertError(t, err, "http://admin:xxxxx@")
+	assertError(t, err, "403 Forbidden")
+	if strings.Contains(err.Error(), "hunter2") {
+		t.Errorf("error %q contains the password", err)
+	}
+}
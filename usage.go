@@ -0,0 +1,58 @@
+package amalgam
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/spf13/pflag"
+)
+
+// FlagGroupAnnotation is the flag annotation set to the group given by a
+// field's `group` tag option, eg. for grouping the flags in cobra usage
+// templates.
+const FlagGroupAnnotation = "cobra_annotation_flag_group"
+
+// UsageByGroup returns the flag usage, with the flags for each nested struct
+// grouped under the name of the top-level struct field (eg. all of the
+// `Database.*` flags under `Database:`), or the group given by the `group`
+// tag option.  The flags for top-level fields, and any other flags in the
+// flag set, are listed first without a heading.
+func (a *Amalgam) UsageByGroup() string {
+	groupOf := make(map[string]string)
+	for field, info := range a.fields {
+		if idx := strings.Index(field, "."); idx >= 0 && !info.excluded() {
+			groupOf[info.flagName] = field[:idx]
+		}
+	}
+
+	groups := make(map[string]*pflag.FlagSet)
+	a.flagSet.VisitAll(func(flag *pflag.Flag) {
+		group := groupOf[flag.Name]
+		if annotation := flag.Annotations[FlagGroupAnnotation]; len(annotation) > 0 {
+			group = annotation[0]
+		}
+		if groups[group] == nil {
+			groups[group] = pflag.NewFlagSet(group, pflag.ContinueOnError)
+		}
+		groups[group].AddFlag(flag)
+	})
+
+	names := make([]string, 0, len(groups))
+	for name := range groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		if b.Len() > 0 {
+			b.WriteString("\n")
+		}
+		if name != "" {
+			b.WriteString(name + ":\n")
+		}
+		b.WriteString(groups[name].FlagUsages())
+	}
+
+	return b.String()
+}
@@ -0,0 +1,53 @@
+package amalgam
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFlagGroupAnnotation(t *testing.T) {
+	var config struct {
+		Host string `amalgam:",group=Networking"`
+		Port int    `amalgam:",group=Networking"`
+		Name string
+	}
+	a := newTestAmalgam(t, &config, nil)
+
+	for _, name := range []string{"host", "port"} {
+		got := a.flagSet.Lookup(name).Annotations[FlagGroupAnnotation]
+		if want := []string{"Networking"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("--%s group annotation = %q, want %q", name, got, want)
+		}
+	}
+	if got := a.flagSet.Lookup("name").Annotations; got[FlagGroupAnnotation] != nil {
+		t.Errorf("--name annotations = %q, want no group", got)
+	}
+}
+
+func TestUsageByGroup(t *testing.T) {
+	var config struct {
+		Name     string `amalgam:",Service name"`
+		Token    string `amalgam:",group=Auth,API token"`
+		Database struct {
+			Host string `amalgam:",Database host"`
+			Port int    `amalgam:",Database port"`
+		}
+	}
+	a := newTestAmalgam(t, &config, nil)
+
+	// Ungrouped flags come first, then the groups in order, with the nested
+	// struct's flags under its field name.
+	want := `  -c, --config string   config file to use
+      --name string     Service name
+
+Auth:
+      --token string   API token
+
+Database:
+      --database-host string   Database host
+      --database-port int      Database port
+`
+	if got := a.UsageByGroup(); got != want {
+		t.Errorf("got usage:\n%s\nwant:\n%s", got, want)
+	}
+}
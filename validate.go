@@ -0,0 +1,240 @@
+package amalgam
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// WithDeferredValidation skips the checks of required fields and validation
+// when loading, until Finalize is called, eg. to set fields programmatically
+// between loading the config in phases and checking it.
+func WithDeferredValidation() func(*Amalgam) {
+	return func(a *Amalgam) {
+		a.deferValidation = true
+	}
+}
+
+// Finalize runs the checks skipped by WithDeferredValidation: that the fields
+// tagged with `requiredflag` were provided (with Set counting as providing
+// them), the validation in the field tags, and the config object's Validate
+// method.
+func (a *Amalgam) Finalize() error {
+	return a.named(a.finalize())
+}
+
+// finalize does the work of Finalize.
+func (a *Amalgam) finalize() error {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	if err := a.checkRequiredFlags(); err != nil {
+		return err
+	}
+	if err := a.validate(); err != nil {
+		return err
+	}
+	return a.validateConfig()
+}
+
+// checkRequiredFlags returns an error listing the fields tagged with
+// `requiredflag` which weren't explicitly provided by a flag, the config file,
+// the environment, a value file, the secrets directory or Set.
+func (a *Amalgam) checkRequiredFlags() error {
+	var missing []string
+	for field, info := range a.fields {
+		if info.excluded() || !info.options.has("requiredflag") {
+			continue
+		}
+
+		if flag := a.flagSet.Lookup(info.flagName); flag != nil && flag.Changed {
+			continue
+		}
+		if _, file := a.fileValues[field]; file || a.inFile(field) || a.inEnv(field) || a.setFields[field] {
+			continue
+		}
+		missing = append(missing, "--"+info.flagName)
+	}
+
+	if len(missing) == 0 {
+		return nil
+	}
+	sort.Strings(missing)
+
+	return fmt.Errorf("required flags not provided: %s", strings.Join(missing, ", "))
+}
+
+// validate checks the loaded config object against the validation options
+// in the field tags, and returns an ErrorList of every violation.
+func (a *Amalgam) validate() error {
+	fields := a.sortedFields()
+	mutexGroups := make(map[string][]string)
+	exactGroups := make(map[string][]string)
+	exactSet := make(map[string][]string)
+
+	var errs ErrorList
+	for _, field := range fields {
+		info := a.fields[field]
+		if info.excluded() {
+			continue
+		}
+
+		if v := a.fieldValue(info); v.IsValid() && info.options.has("keyvalue") {
+			if pairs, ok := v.Interface().([]string); ok {
+				for _, pair := range pairs {
+					if err := parsePair(pair); err != nil {
+						errs = append(errs, fmt.Errorf("%s: %v", field, err))
+					}
+				}
+			}
+		}
+
+		if values, ok := info.options["oneof"]; ok && values != "" {
+			if err := checkOneOf(a.fieldValue(info), strings.Split(values, "|")); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %v", field, err))
+			}
+		}
+
+		// Port ranges given as maps in the config file aren't parsed, so
+		// check them here.
+		if v := a.fieldValue(info); v.IsValid() && v.Type() == portRangeType {
+			if err := v.Interface().(PortRange).check(); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %v", field, err))
+			}
+		}
+
+		if group := info.options["mutexgroup"]; group != "" && !isZero(a.fieldValue(info)) {
+			mutexGroups[group] = append(mutexGroups[group], field)
+		}
+		if group := info.options["requireexactlyone"]; group != "" {
+			exactGroups[group] = append(exactGroups[group], field)
+			if !isZero(a.fieldValue(info)) {
+				exactSet[group] = append(exactSet[group], field)
+			}
+		}
+
+		if with, ok := info.options["requiredwith"]; ok && isZero(a.fieldValue(info)) {
+			for _, sibling := range strings.Split(with, "|") {
+				set, err := a.siblingSet(field, sibling)
+				if err != nil {
+					errs = append(errs, err)
+					break
+				}
+				if set {
+					errs = append(errs, fmt.Errorf("%s is required when %s is set", field, sibling))
+					break
+				}
+			}
+		}
+		if without, ok := info.options["requiredwithout"]; ok && isZero(a.fieldValue(info)) {
+			for _, sibling := range strings.Split(without, "|") {
+				set, err := a.siblingSet(field, sibling)
+				if err != nil {
+					errs = append(errs, err)
+					break
+				}
+				if !set {
+					errs = append(errs, fmt.Errorf("%s is required when %s is not set", field, sibling))
+					break
+				}
+			}
+		}
+	}
+
+	for _, group := range sortedKeys(mutexGroups) {
+		if set := mutexGroups[group]; len(set) > 1 {
+			errs = append(errs, fmt.Errorf("only one of %s may be set (group %s)", strings.Join(set, ", "), group))
+		}
+	}
+	for _, group := range sortedKeys(exactGroups) {
+		members, set := exactGroups[group], exactSet[group]
+		switch {
+		case len(set) == 0:
+			errs = append(errs, fmt.Errorf("one of %s must be set (group %s)", strings.Join(members, ", "), group))
+		case len(set) > 1:
+			errs = append(errs, fmt.Errorf("only one of %s may be set, but %s are (group %s)", strings.Join(members, ", "), strings.Join(set, ", "), group))
+		}
+	}
+
+	return errs.err()
+}
+
+// validator is implemented by config objects which validate themselves.
+type validator interface {
+	Validate() error
+}
+
+// validateConfig calls the config object's Validate method, if it has one,
+// once its fields have passed the validation in their tags.
+func (a *Amalgam) validateConfig() error {
+	if v, ok := a.configObj.(validator); ok {
+		return v.Validate()
+	}
+	return nil
+}
+
+func sortedKeys(m map[string][]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// siblingSet reports whether the named field in the same struct as field has
+// a non-zero value.
+func (a *Amalgam) siblingSet(field, sibling string) (bool, error) {
+	name := sibling
+	if idx := strings.LastIndex(field, "."); idx >= 0 {
+		name = field[:idx+1] + sibling
+	}
+
+	info, ok := a.fields[name]
+	if !ok {
+		return false, fmt.Errorf("%s: unknown field %q in tag", field, sibling)
+	}
+	return !isZero(a.fieldValue(info)), nil
+}
+
+// checkOneOf returns an error if v, or any element of a slice v, isn't one of
+// the accepted values.  The zero value, ie. an unset field, is accepted.
+func checkOneOf(v reflect.Value, values []string) error {
+	if isZero(v) {
+		return nil
+	}
+	if v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.String {
+		for i := 0; i < v.Len(); i++ {
+			if err := checkOneOf(v.Index(i), values); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	s := fmt.Sprint(v.Interface())
+	for _, value := range values {
+		if s == value {
+			return nil
+		}
+	}
+	return fmt.Errorf("%q is not one of %s", s, strings.Join(values, ", "))
+}
+
+// sortedFields returns the names of the fields in sorted order.
+func (a *Amalgam) sortedFields() []string {
+	fields := make([]string, 0, len(a.fields))
+	for field := range a.fields {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+func isZero(v reflect.Value) bool {
+	if !v.IsValid() {
+		return true
+	}
+	return v.IsZero()
+}
@@ -0,0 +1,179 @@
+package amalgam
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+type tlsConfig struct {
+	TLSCert string
+	TLSKey  string `amalgam:",requiredwith=TLSCert"`
+	Token   string
+	APIKey  string `amalgam:",requiredwithout=Token"`
+}
+
+func TestRequiredWith(t *testing.T) {
+	var config tlsConfig
+	a := newTestAmalgam(t, &config, []string{"--tls-cert", "cert.pem", "--token", "t"})
+	assertError(t, a.Load(strings.NewReader("")), "TLSKey is required when TLSCert is set")
+
+	config = tlsConfig{}
+	a = newTestAmalgam(t, &config, []string{"--tls-cert", "cert.pem", "--tls-key", "key.pem", "--token", "t"})
+	loadYAML(t, a, "")
+}
+
+func TestRequiredWithout(t *testing.T) {
+	var config tlsConfig
+	a := newTestAmalgam(t, &config, nil)
+	assertError(t, a.Load(strings.NewReader("")), "APIKey is required when Token is not set")
+
+	config = tlsConfig{}
+	a = newTestAmalgam(t, &config, []string{"--api-key", "k"})
+	loadYAML(t, a, "")
+}
+
+func TestRequiredErrorsAggregate(t *testing.T) {
+	var config tlsConfig
+	a := newTestAmalgam(t, &config, []string{"--tls-cert", "cert.pem"})
+	err := a.Load(strings.NewReader(""))
+	assertError(t, err, "TLSKey is required when TLSCert is set")
+	assertError(t, err, "APIKey is required when Token is not set")
+}
+
+func TestRequiredWithUnknownField(t *testing.T) {
+	var config struct {
+		TLSKey string `amalgam:",requiredwith=Certificate"`
+		Token  string
+		APIKey string `amalgam:",requiredwithout=Token"`
+	}
+	a := newTestAmalgam(t, &config, nil)
+	err := a.Load(strings.NewReader(""))
+	assertError(t, err, `TLSKey: unknown field "Certificate" in tag`)
+	assertError(t, err, "APIKey is required when Token is not set")
+}
+
+func TestMutexGroup(t *testing.T) {
+	type outputConfig struct {
+		JSONOutput bool `amalgam:",mutexgroup=output"`
+		YAMLOutput bool `amalgam:",mutexgroup=output"`
+	}
+	for _, args := range [][]string{
+		{},
+		{"--json-output"},
+		{"--yaml-output"},
+	} {
+		var config outputConfig
+		a := newTestAmalgam(t, &config, args)
+		if err := a.Load(strings.NewReader("")); err != nil {
+			t.Errorf("%v: %v", args, err)
+		}
+	}
+
+	var config outputConfig
+	a := newTestAmalgam(t, &config, []string{"--json-output", "--yaml-output"})
+	assertError(t, a.Load(strings.NewReader("")), "only one of JSONOutput, YAMLOutput may be set (group output)")
+}
+
+func TestRequireExactlyOne(t *testing.T) {
+	type authConfig struct {
+		Token   string `amalgam:",requireexactlyone=auth"`
+		KeyFile string `amalgam:",requireexactlyone=auth"`
+		OAuth   bool   `amalgam:",requireexactlyone=auth"`
+	}
+	for _, test := range []struct {
+		args []string
+		want string
+	}{
+		{nil, "one of KeyFile, OAuth, Token must be set (group auth)"},
+		{[]string{"--token", "t"}, ""},
+		{[]string{"--o-auth"}, ""},
+		{[]string{"--token", "t", "--key-file", "k"}, "only one of KeyFile, OAuth, Token may be set, but KeyFile, Token are (group auth)"},
+	} {
+		var config authConfig
+		a := newTestAmalgam(t, &config, test.args)
+		err := a.Load(strings.NewReader(""))
+		if test.want == "" {
+			if err != nil {
+				t.Errorf("%v: %v", test.args, err)
+			}
+			continue
+		}
+		assertError(t, err, test.want)
+	}
+}
+
+type selfValidatingConfig struct {
+	MinConns int
+	MaxConns int
+}
+
+func (c *selfValidatingConfig) Validate() error {
+	if c.MinConns > c.MaxConns {
+		return fmt.Errorf("min conns %d exceeds max conns %d", c.MinConns, c.MaxConns)
+	}
+	return nil
+}
+
+func TestValidateMethod(t *testing.T) {
+	var config selfValidatingConfig
+	a := newTestAmalgam(t, &config, []string{"--min-conns", "10", "--max-conns", "5"})
+	assertError(t, a.Load(strings.NewReader("")), "min conns 10 exceeds max conns 5")
+
+	config = selfValidatingConfig{}
+	a = newTestAmalgam(t, &config, []string{"--min-conns", "1", "--max-conns", "5"})
+	if err := a.Load(strings.NewReader("")); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestDeferredValidation(t *testing.T) {
+	var config struct {
+		Token   string `amalgam:",requiredflag"`
+		TLSCert string
+		TLSKey  string `amalgam:",requiredwith=TLSCert"`
+	}
+	a := newTestAmalgam(t, &config, nil, WithDeferredValidation())
+	loadYAML(t, a, "")
+	assertError(t, a.Finalize(), "required flags not provided: --token")
+
+	if err := a.Set("token", "from-vault"); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Finalize(); err != nil {
+		t.Errorf("Finalize() after setting the required field: %v", err)
+	}
+
+	if err := a.Set("tlscert", "cert.pem"); err != nil {
+		t.Fatal(err)
+	}
+	assertError(t, a.Finalize(), "TLSKey is required when TLSCert is set")
+}
+
+func TestOneOf(t *testing.T) {
+	type oneOfConfig struct {
+		Format string   `amalgam:",oneof=json|yaml"`
+		Levels []string `amalgam:",oneof=debug|info|warn"`
+	}
+
+	for _, test := range []struct {
+		args []string
+		err  string
+	}{
+		{args: nil},
+		{args: []string{"--format", "json", "--levels", "debug,warn"}},
+		{args: []string{"--format", "xml"}, err: `Format: "xml" is not one of json, yaml`},
+		{args: []string{"--levels", "info,trace"}, err: `Levels: "trace" is not one of debug, info, warn`},
+	} {
+		var config oneOfConfig
+		a := newTestAmalgam(t, &config, test.args)
+		err := a.Load(strings.NewReader(""))
+		if test.err == "" {
+			if err != nil {
+				t.Errorf("%q: %v", test.args, err)
+			}
+			continue
+		}
+		assertError(t, err, test.err)
+	}
+}
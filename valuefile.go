@@ -0,0 +1,36 @@
+package amalgam
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// readValueFiles reads the values of the fields tagged with the `valuefile`
+// option from their files, with the contents trimmed of surrounding
+// whitespace.  Fields given by a flag, --set or the environment are skipped,
+// as are missing files, unless the field is tagged with `requiredflag`.
+func (a *Amalgam) readValueFiles() (map[string]string, error) {
+	values := make(map[string]string)
+	if a.flagsOnly {
+		return values, nil
+	}
+
+	for field, info := range a.fields {
+		name := info.options["valuefile"]
+		if info.excluded() || name == "" || a.flagOrSetValue(field) || a.inEnv(field) {
+			continue
+		}
+
+		data, err := ioutil.ReadFile(name)
+		if os.IsNotExist(err) && !info.options.has("requiredflag") {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", field, err)
+		}
+		values[field] = strings.TrimSpace(string(data))
+	}
+	return values, nil
+}
@@ -0,0 +1,55 @@
+package amalgam
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// valueFileConfig returns a pointer to a struct with a License field read
+// from the value file, with the extra tag options.
+func valueFileConfig(path, options string) reflect.Value {
+	t := reflect.StructOf([]reflect.StructField{
+		{Name: "Name", Type: reflect.TypeOf("")},
+		{Name: "License", Type: reflect.TypeOf(""), Tag: reflect.StructTag(`amalgam:",valuefile=` + path + options + `"`)},
+	})
+	return reflect.New(t)
+}
+
+func TestValueFile(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+	path := writeFile(t, dir, "license", "  ABC-123\n")
+
+	config := valueFileConfig(path, "")
+	a := newTestAmalgam(t, config.Interface(), nil)
+	loadYAML(t, a, "name: svc\nlicense: from-config\n")
+	if got := config.Elem().Field(1).String(); got != "ABC-123" {
+		t.Errorf("License = %q, want the value file over the config file", got)
+	}
+
+	config = valueFileConfig(path, "")
+	a = newTestAmalgam(t, config.Interface(), []string{"--license", "from-flag"})
+	loadYAML(t, a, "")
+	if got := config.Elem().Field(1).String(); got != "from-flag" {
+		t.Errorf("License = %q, want the flag over the value file", got)
+	}
+}
+
+func TestMissingValueFile(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+	path := filepath.Join(dir, "missing")
+
+	config := valueFileConfig(path, "")
+	a := newTestAmalgam(t, config.Interface(), nil)
+	loadYAML(t, a, "license: from-config\n")
+	if got := config.Elem().Field(1).String(); got != "from-config" {
+		t.Errorf("License = %q, want a missing value file skipped", got)
+	}
+
+	config = valueFileConfig(path, ",requiredflag")
+	a = newTestAmalgam(t, config.Interface(), nil)
+	assertError(t, a.Load(strings.NewReader("")), "License: open "+path)
+}
@@ -0,0 +1,61 @@
+package amalgam
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/spf13/pflag"
+)
+
+// parseIPMask parses an IPv4 mask in either dotted or hex form.
+func parseIPMask(s string) (net.IPMask, error) {
+	mask := pflag.ParseIPv4Mask(strings.TrimSpace(s))
+	if mask == nil {
+		return nil, fmt.Errorf("invalid IP mask %q", s)
+	}
+	return mask, nil
+}
+
+// ipMaskSliceValue is a pflag.Value for a []net.IPMask flag.  Masks are
+// comma-separated, and repeating the flag appends to the slice.
+type ipMaskSliceValue struct {
+	value   []net.IPMask
+	changed bool
+}
+
+func newIPMaskSliceValue(val []net.IPMask) *ipMaskSliceValue {
+	return &ipMaskSliceValue{value: val}
+}
+
+func (s *ipMaskSliceValue) Set(val string) error {
+	var masks []net.IPMask
+	for _, m := range strings.Split(val, ",") {
+		mask, err := parseIPMask(m)
+		if err != nil {
+			return err
+		}
+		masks = append(masks, mask)
+	}
+
+	if s.changed {
+		s.value = append(s.value, masks...)
+	} else {
+		s.value = masks
+	}
+	s.changed = true
+
+	return nil
+}
+
+func (s *ipMaskSliceValue) Type() string {
+	return "ipMaskSlice"
+}
+
+func (s *ipMaskSliceValue) String() string {
+	masks := make([]string, len(s.value))
+	for i, mask := range s.value {
+		masks[i] = net.IP(mask).String()
+	}
+	return "[" + strings.Join(masks, ",") + "]"
+}
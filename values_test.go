@@ -0,0 +1,66 @@
+package amalgam
+
+import (
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestIPMaskFields(t *testing.T) {
+	var config struct {
+		Mask  net.IPMask
+		Masks []net.IPMask
+	}
+	a := newTestAmalgam(t, &config, []string{"--mask", "255.255.0.0", "--masks", "255.0.0.0,ffffff00"})
+	loadYAML(t, a, "")
+
+	if want := net.IPv4Mask(255, 255, 0, 0); !reflect.DeepEqual(config.Mask, want) {
+		t.Errorf("Mask = %v, want %v", config.Mask, want)
+	}
+	want := []net.IPMask{net.IPv4Mask(255, 0, 0, 0), net.IPv4Mask(255, 255, 255, 0)}
+	if !reflect.DeepEqual(config.Masks, want) {
+		t.Errorf("Masks = %v, want %v", config.Masks, want)
+	}
+}
+
+func TestIPMaskFieldsFromFile(t *testing.T) {
+	var config struct {
+		Mask  net.IPMask
+		Masks []net.IPMask
+	}
+	a := newTestAmalgam(t, &config, nil)
+	loadYAML(t, a, "mask: 255.255.255.0\nmasks:\n  - 255.0.0.0\n  - ffff0000\n")
+
+	if want := net.IPv4Mask(255, 255, 255, 0); !reflect.DeepEqual(config.Mask, want) {
+		t.Errorf("Mask = %v, want %v", config.Mask, want)
+	}
+	want := []net.IPMask{net.IPv4Mask(255, 0, 0, 0), net.IPv4Mask(255, 255, 0, 0)}
+	if !reflect.DeepEqual(config.Masks, want) {
+		t.Errorf("Masks = %v, want %v", config.Masks, want)
+	}
+}
+
+func TestIPMaskSliceFlagAppends(t *testing.T) {
+	var config struct {
+		Masks []net.IPMask
+	}
+	a := newTestAmalgam(t, &config, []string{"--masks", "255.0.0.0", "--masks", "255.255.0.0"})
+	loadYAML(t, a, "")
+
+	if len(config.Masks) != 2 {
+		t.Errorf("Masks = %v, want both masks", config.Masks)
+	}
+}
+
+func TestInvalidIPMask(t *testing.T) {
+	var config struct {
+		Mask  net.IPMask
+		Masks []net.IPMask
+	}
+	a := newTestAmalgam(t, &config, []string{"--masks", "bogus"})
+	assertError(t, a.Load(nil), `invalid IP mask "bogus"`)
+
+	a = newTestAmalgam(t, &config, nil)
+	assertError(t, a.Load(strings.NewReader("mask: 300.0.0.0\n")), `invalid IP mask "300.0.0.0"`)
+}
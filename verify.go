@@ -0,0 +1,37 @@
+package amalgam
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+)
+
+// LoadFileVerified hydrates the config from the file at path, after checking
+// that its SHA-256 digest matches the hex-encoded digest, to guard against
+// the file being tampered with.  The file isn't parsed if it doesn't match.
+// It isn't re-read by Reload, as the new contents wouldn't be verified.
+func (a *Amalgam) LoadFileVerified(path, sha256hex string) error {
+	return a.named(a.loadFileVerified(path, sha256hex))
+}
+
+// loadFileVerified does the work of LoadFileVerified.
+func (a *Amalgam) loadFileVerified(path, sha256hex string) error {
+	want, err := hex.DecodeString(sha256hex)
+	if err != nil || len(want) != sha256.Size {
+		return fmt.Errorf("invalid sha256 digest %q", sha256hex)
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	got := sha256.Sum256(data)
+	if subtle.ConstantTimeCompare(got[:], want) != 1 {
+		return fmt.Errorf("config file %s doesn't match its sha256 digest", path)
+	}
+
+	return a.loadDocs(configDoc{r: bytes.NewReader(data), format: a.formatFor(path), name: path})
+}
@@ -0,0 +1,37 @@
+package amalgam
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+)
+
+func TestLoadFileVerified(t *testing.T) {
+	dir, cleanup := tempDir(t)
+	defer cleanup()
+	content := "name: svc\n"
+	path := writeFile(t, dir, "config.yaml", content)
+	sum := sha256.Sum256([]byte(content))
+	digest := hex.EncodeToString(sum[:])
+
+	var config struct {
+		Name string
+	}
+	a := newTestAmalgam(t, &config, nil, WithConfigType(""))
+	if err := a.LoadFileVerified(path, digest); err != nil {
+		t.Fatal(err)
+	}
+	if config.Name != "svc" {
+		t.Errorf("Name = %q, want %q", config.Name, "svc")
+	}
+
+	writeFile(t, dir, "config.yaml", "name: tampered\n")
+	config.Name = ""
+	a = newTestAmalgam(t, &config, nil, WithConfigType(""))
+	assertError(t, a.LoadFileVerified(path, digest), "doesn't match its sha256 digest")
+	if config.Name != "" {
+		t.Errorf("Name = %q, want the tampered file not to be parsed", config.Name)
+	}
+
+	assertError(t, a.LoadFileVerified(path, "abc"), `invalid sha256 digest "abc"`)
+}